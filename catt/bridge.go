@@ -1,142 +1,440 @@
 package catt
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/logging"
 	"github.com/catt-ha/catt-go/catt/types"
 )
 
-var log = logrus.New()
+var log = logging.Logger("bridge")
+
+const watchBuffer = 64
+
+// DefaultBinding is the name of the binding passed to NewBridge.
+const DefaultBinding = "default"
 
+// Bridge connects bindings to a bus. Bindings can be added and removed
+// while the bridge runs.
 type Bridge struct {
-	done chan struct{}
+	bus      types.Bus
+	done     chan struct{}
+	doneOnce sync.Once
+
+	mu       sync.Mutex
+	items    map[string]types.Item
+	owners   map[string]string
+	bindings map[string]*attachedBinding
+	watchers map[chan types.Notification]struct{}
+}
+
+type attachedBinding struct {
+	binding types.Binding
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewBridge starts a bridge between a bus and a binding, which may be nil
+// if bindings are only added later.
+func NewBridge(bus types.Bus, binding types.Binding) *Bridge {
+	b := &Bridge{
+		bus:      bus,
+		done:     make(chan struct{}),
+		items:    make(map[string]types.Item),
+		owners:   make(map[string]string),
+		bindings: make(map[string]*attachedBinding),
+		watchers: make(map[chan types.Notification]struct{}),
+	}
+	b.busToBinding(bus.Messages())
+
+	if binding != nil {
+		b.AddBinding(DefaultBinding, binding)
+	}
+
+	return b
+}
+
+// AddBinding attaches a binding under a unique name.
+func (b *Bridge) AddBinding(name string, binding types.Binding) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.bindings[name]; ok {
+		return fmt.Errorf("binding %s already exists", name)
+	}
+
+	a := &attachedBinding{
+		binding: binding,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	b.bindings[name] = a
+	b.bindingToBus(name, a)
+
+	return nil
+}
+
+// RemoveBinding detaches a binding, retires its items as if the binding
+// had removed them, and closes the binding if it has a Close method.
+func (b *Bridge) RemoveBinding(name string) error {
+	b.mu.Lock()
+	a, ok := b.bindings[name]
+	b.mu.Unlock()
+
+	if !ok || !b.detach(name, a) {
+		return fmt.Errorf("no such binding: %s", name)
+	}
+
+	close(a.stop)
+	<-a.stopped
+
+	return b.retire(name, a)
+}
+
+// detach removes a binding from the bridge, unless it was removed or
+// replaced already.
+func (b *Bridge) detach(name string, a *attachedBinding) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.bindings[name] != a {
+		return false
+	}
+	delete(b.bindings, name)
+	return true
 }
 
-func NewBridge(bus types.Bus, binding types.Binding) Bridge {
-	messages := bus.Messages()
-	notifications := binding.Notifications()
+// retire removes the items of a detached binding and closes it.
+func (b *Bridge) retire(name string, a *attachedBinding) error {
+	b.mu.Lock()
+	var items []types.Item
+	for itemName, owner := range b.owners {
+		if owner == name {
+			items = append(items, b.items[itemName])
+		}
+	}
+	b.mu.Unlock()
+
+	for _, item := range items {
+		b.handle(name, types.Notification{
+			Type: types.RemovedNotification,
+			Item: item,
+		})
+	}
+
+	switch c := a.binding.(type) {
+	case interface{ Close() error }:
+		return c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+	return nil
+}
 
-	done := make(chan struct{})
-	busToBinding(messages, binding, done)
-	bindingToBus(notifications, bus, done)
+// Bindings returns the names of the attached bindings, sorted.
+func (b *Bridge) Bindings() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	return Bridge{done}
+	names := make([]string, 0, len(b.bindings))
+	for name := range b.bindings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-func (b Bridge) Run() {
+func (b *Bridge) Run() {
 	<-b.done
 }
 
-func busToBinding(msgs <-chan types.Message, binding types.Binding, done chan struct{}) {
+func (b *Bridge) stop() {
+	b.doneOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// Health reports an error once the bridge has stopped receiving from the
+// bus.
+func (b *Bridge) Health() error {
+	select {
+	case <-b.done:
+		return errors.New("bridge stopped")
+	default:
+		return nil
+	}
+}
+
+// Items returns the items announced by the bindings, sorted by name.
+func (b *Bridge) Items() []types.Item {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items := make([]types.Item, 0, len(b.items))
+	for _, item := range b.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].GetName() < items[j].GetName()
+	})
+	return items
+}
+
+func (b *Bridge) Item(name string) types.Item {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.items[name]
+}
+
+// Watch returns a channel receiving every notification the bridge handles.
+// Watchers that fall behind miss notifications rather than stalling the
+// bridge.
+func (b *Bridge) Watch() (<-chan types.Notification, func()) {
+	ch := make(chan types.Notification, watchBuffer)
+
+	b.mu.Lock()
+	b.watchers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.watchers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *Bridge) track(owner string, notification types.Notification) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	item := notification.Item
+	switch notification.Type {
+	case types.AddedNotification, types.ChangedNotification:
+		b.items[item.GetName()] = item
+		b.owners[item.GetName()] = owner
+	case types.RemovedNotification:
+		delete(b.items, item.GetName())
+		delete(b.owners, item.GetName())
+	}
+
+	for ch := range b.watchers {
+		select {
+		case ch <- notification:
+		default:
+			log.WithFields(logging.ItemFields(item)).Warn("watcher too slow, dropping notification")
+		}
+	}
+}
+
+// publish sends a message to the bus and counts the outcome.
+func publish(bus types.Bus, message types.Message) error {
+	if err := bus.Publish(message); err != nil {
+		publishErrors.WithLabelValues(message.Type.String()).Inc()
+		return err
+	}
+	messagesPublished.WithLabelValues(message.Type.String()).Inc()
+	return nil
+}
+
+// bindingFor returns the binding owning an item.
+func (b *Bridge) bindingFor(itemName string) types.Binding {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	a, ok := b.bindings[b.owners[itemName]]
+	if !ok {
+		return nil
+	}
+	return a.binding
+}
+
+// SendCommand coerces a command to the item's declared type, checks it
+// against the item's constraints and sets the item's value. INCREASE and
+// DECREASE step the current value of number and percent items.
+func (b *Bridge) SendCommand(msg types.Message) error {
+	var item types.Item
+	if binding := b.bindingFor(msg.ItemName); binding != nil {
+		item = binding.GetValue(msg.ItemName)
+	}
+	if item == nil {
+		commandErrors.WithLabelValues("unknown_item").Inc()
+		return fmt.Errorf("%w: %s", types.ErrNoSuchItem, msg.ItemName)
+	}
+	if msg.Value == nil {
+		commandErrors.WithLabelValues("invalid_value").Inc()
+		return fmt.Errorf("%w: missing value", types.ErrInvalidValue)
+	}
+
+	meta := item.GetMeta()
+	backend := ""
+	if meta != nil {
+		backend = meta.Backend
+		if meta.ReadOnly {
+			commandErrors.WithLabelValues("read_only").Inc()
+			return fmt.Errorf("%w: %s", types.ErrReadOnly, msg.ItemName)
+		}
+	}
+
+	value, stepped, err := meta.ApplyStep(*msg.Value, item.GetValue)
+	if !stepped {
+		value, err = meta.Coerce(*msg.Value, msg.Raw)
+	}
+	if err == nil {
+		err = meta.Check(value)
+	}
+	if err != nil {
+		commandErrors.WithLabelValues("invalid_value").Inc()
+		return fmt.Errorf("%w: %v", types.ErrInvalidValue, err)
+	}
+
+	start := time.Now()
+	err = item.SetValue(value)
+	setValueDuration.WithLabelValues(backend).Observe(time.Since(start).Seconds())
+	if err != nil {
+		commandErrors.WithLabelValues("set_value").Inc()
+		return fmt.Errorf("setting %s: %w", msg.ItemName, err)
+	}
+	return nil
+}
+
+func (b *Bridge) busToBinding(msgs <-chan types.Message) {
 	go func() {
-		defer close(done)
+		defer b.stop()
 		for msg := range msgs {
-			var name string
-			var value types.Value
-			switch msg.Type {
-			case types.CommandMessage:
-				name = msg.ItemName
-				value = *msg.Value
-			default:
-				log.WithFields(logrus.Fields{
-					"message": msg,
-				}).Warn("received non-command message")
-				continue
-			}
+			messagesReceived.WithLabelValues(msg.Type.String()).Inc()
 
-			item := binding.GetValue(name)
-			if item == nil {
-				log.WithFields(logrus.Fields{
-					"item_name": name,
-				}).Warn("received message for non-existant item")
+			if msg.Type != types.CommandMessage {
+				log.WithFields(logging.MessageFields(msg)).Warn("received non-command message")
 				continue
 			}
 
-			err := item.SetValue(value)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"item":  item,
-					"value": value,
-				}).Warn("error setting item value")
+			if err := b.SendCommand(msg); err != nil {
+				log.WithFields(logging.MessageFields(msg)).WithError(err).Warn("command rejected")
+				b.publishError(msg.ItemName, err)
 			}
 		}
 	}()
 }
 
-func bindingToBus(notifications <-chan types.Notification, bus types.Bus, done chan struct{}) {
-	go func() {
-		defer close(done)
-		for notification := range notifications {
-			var skipState, newSub, removeSub bool
-			var meta *types.Meta
-
-			item := notification.Item
-
-			switch notification.Type {
-			case types.ChangedNotification:
-			case types.AddedNotification:
-				meta = item.GetMeta()
-				skipState = true
-				newSub = true
-			case types.RemovedNotification:
-				removeSub = true
-				skipState = true
-			default:
-				log.WithFields(logrus.Fields{
-					"notification": notification,
-				}).Warn("invalid notification type")
-				continue
-			}
+// publishError reports a failed command on the item's error topic.
+func (b *Bridge) publishError(itemName string, cmdErr error) {
+	if err := publish(b.bus, types.Message{
+		Type:     types.ErrorMessage,
+		ItemName: itemName,
+		Error:    cmdErr.Error(),
+	}); err != nil {
+		log.WithFields(logrus.Fields{
+			logging.FieldItem: itemName,
+		}).WithError(err).Warn("error publish error")
+	}
+}
 
-			if meta != nil {
-				if err := bus.Publish(types.Message{
-					Type:     types.MetaMessage,
-					ItemName: item.GetName(),
-					Meta:     meta,
-				}); err != nil {
-					log.WithFields(logrus.Fields{
-						"error": err,
-					}).Warn("meta publish error")
+func (b *Bridge) bindingToBus(name string, a *attachedBinding) {
+	notifications := a.binding.Notifications()
+	go func() {
+		defer close(a.stopped)
+		for {
+			select {
+			case notification, ok := <-notifications:
+				if !ok {
+					// Only this binding is gone, the others carry on.
+					if b.detach(name, a) {
+						log.WithFields(logrus.Fields{
+							"binding": name,
+						}).Warn("binding stopped, removing it")
+						if err := b.retire(name, a); err != nil {
+							log.WithFields(logrus.Fields{
+								"binding": name,
+							}).WithError(err).Warn("error closing binding")
+						}
+					}
+					return
 				}
+				b.handle(name, notification)
+			case <-a.stop:
+				return
 			}
+		}
+	}()
+}
 
-			if newSub {
-				if err := bus.Subscribe(item.GetName(), types.CommandSub); err != nil {
-					log.WithFields(logrus.Fields{
-						"error": err,
-					}).Warn("subscribe error")
-				}
-			}
+// handle forwards a notification from the binding called owner to the bus.
+func (b *Bridge) handle(owner string, notification types.Notification) {
+	var skipState, newSub, removeSub bool
+	var meta *types.Meta
 
-			if removeSub {
-				if err := bus.Unsubscribe(item.GetName(), types.CommandSub); err != nil {
-					log.WithFields(logrus.Fields{
-						"error": err,
-					}).Warn("unsubscribe error")
-				}
-			}
+	item := notification.Item
+	notificationsHandled.WithLabelValues(notification.Type.String()).Inc()
 
-			if skipState {
-				continue
-			}
+	switch notification.Type {
+	case types.ChangedNotification:
+	case types.AddedNotification:
+		meta = item.GetMeta()
+		skipState = true
+		newSub = true
+	case types.RemovedNotification:
+		removeSub = true
+		skipState = true
+	default:
+		log.WithFields(logging.ItemFields(item)).WithFields(logrus.Fields{
+			"notification_type": notification.Type,
+		}).Warn("invalid notification type")
+		return
+	}
 
-			value, err := item.GetValue()
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"error": err,
-					"item":  item,
-				}).Warn("error getting item value")
-				continue
-			}
+	b.track(owner, notification)
 
-			if err := bus.Publish(types.Message{
-				Type:  types.UpdateMessage,
-				Value: &value,
-			}); err != nil {
-				log.WithFields(logrus.Fields{
-					"error": err,
-				}).Warn("state publish error")
-				continue
+	if meta != nil {
+		if err := publish(b.bus, types.Message{
+			Type:     types.MetaMessage,
+			ItemName: item.GetName(),
+			Meta:     meta,
+		}); err != nil {
+			log.WithFields(logging.ItemFields(item)).WithError(err).Warn("meta publish error")
+		}
+	}
+
+	if newSub {
+		if err := b.bus.Subscribe(item.GetName(), types.CommandSub); err != nil {
+			log.WithFields(logging.ItemFields(item)).WithError(err).Warn("subscribe error")
+		}
+	}
+
+	if removeSub {
+		if err := b.bus.Unsubscribe(item.GetName(), types.CommandSub); err != nil {
+			log.WithFields(logging.ItemFields(item)).WithError(err).Warn("unsubscribe error")
+		}
+		if f, ok := b.bus.(types.Forgetter); ok {
+			if err := f.Forget(item.GetName()); err != nil {
+				log.WithFields(logging.ItemFields(item)).WithError(err).Warn("forget error")
 			}
 		}
-	}()
+	}
+
+	if skipState {
+		return
+	}
+
+	value, err := item.GetValue()
+	if err != nil {
+		log.WithFields(logging.ItemFields(item)).WithError(err).Warn("error getting item value")
+		return
+	}
+
+	if err := publish(b.bus, types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: item.GetName(),
+		Value:    &value,
+	}); err != nil {
+		log.WithFields(logging.ItemFields(item)).WithError(err).Warn("state publish error")
+	}
 }
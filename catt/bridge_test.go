@@ -0,0 +1,351 @@
+package catt
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+type testItem struct {
+	mu    sync.Mutex
+	name  string
+	meta  *types.Meta
+	value types.Value
+	set   chan types.Value
+}
+
+func (i *testItem) GetName() string { return i.name }
+
+func (i *testItem) GetMeta() *types.Meta { return i.meta }
+
+func (i *testItem) GetValue() (types.Value, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.value, nil
+}
+
+func (i *testItem) SetValue(v types.Value) error {
+	i.mu.Lock()
+	i.value = v
+	i.mu.Unlock()
+	i.set <- v
+	return nil
+}
+
+type testBinding struct {
+	notifications chan types.Notification
+	items         map[string]types.Item
+	closed        bool
+}
+
+func newTestBinding(items ...*testItem) *testBinding {
+	b := &testBinding{
+		notifications: make(chan types.Notification),
+		items:         make(map[string]types.Item),
+	}
+	for _, item := range items {
+		b.items[item.name] = item
+	}
+	return b
+}
+
+func (b *testBinding) GetValue(name string) types.Item { return b.items[name] }
+
+func (b *testBinding) Notifications() <-chan types.Notification { return b.notifications }
+
+func (b *testBinding) Close() { b.closed = true }
+
+type subscription struct {
+	name string
+	sub  types.SubType
+}
+
+type testBus struct {
+	mu            sync.Mutex
+	messages      chan types.Message
+	published     chan types.Message
+	subscriptions map[subscription]bool
+	forgotten     map[string]bool
+}
+
+func newTestBus() *testBus {
+	return &testBus{
+		messages:      make(chan types.Message),
+		published:     make(chan types.Message, 16),
+		subscriptions: make(map[subscription]bool),
+		forgotten:     make(map[string]bool),
+	}
+}
+
+func (b *testBus) Publish(msg types.Message) error {
+	b.published <- msg
+	return nil
+}
+
+func (b *testBus) Subscribe(name string, sub types.SubType) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[subscription{name, sub}] = true
+	return nil
+}
+
+func (b *testBus) Unsubscribe(name string, sub types.SubType) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscriptions, subscription{name, sub})
+	return nil
+}
+
+func (b *testBus) Messages() <-chan types.Message { return b.messages }
+
+func (b *testBus) Forget(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.forgotten[name] = true
+	return nil
+}
+
+func (b *testBus) subscribed(name string, sub types.SubType) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscriptions[subscription{name, sub}]
+}
+
+func TestBindings(t *testing.T) {
+	bus := newTestBus()
+	bridge := NewBridge(bus, nil)
+
+	item := &testItem{
+		name: "Lamp",
+		meta: &types.Meta{Backend: "test", ValueType: "bool"},
+		set:  make(chan types.Value, 1),
+	}
+	binding := newTestBinding(item)
+	if err := bridge.AddBinding("test", binding); err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.AddBinding("test", binding); err == nil {
+		t.Fatal("expected an error adding a binding twice")
+	}
+
+	binding.notifications <- types.Notification{Type: types.AddedNotification, Item: item}
+	if msg := bustest.Receive(t, bus.published); msg.Type != types.MetaMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if !bus.subscribed("Lamp", types.CommandSub) {
+		t.Fatal("not subscribed to commands")
+	}
+
+	on := types.NewBoolValue(true)
+	bus.messages <- types.Message{Type: types.CommandMessage, ItemName: "Lamp", Value: &on}
+	select {
+	case v := <-item.set:
+		if b, _ := v.AsBool(); !b {
+			t.Fatalf("unexpected value: %+v", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command not delivered")
+	}
+
+	if err := bridge.RemoveBinding("test"); err != nil {
+		t.Fatal(err)
+	}
+	if !binding.closed {
+		t.Fatal("binding not closed")
+	}
+	if bus.subscribed("Lamp", types.CommandSub) {
+		t.Fatal("still subscribed to commands")
+	}
+	bus.mu.Lock()
+	forgotten := bus.forgotten["Lamp"]
+	bus.mu.Unlock()
+	if !forgotten {
+		t.Fatal("removed item not forgotten by the bus")
+	}
+	if bridge.Item("Lamp") != nil || len(bridge.Bindings()) != 0 {
+		t.Fatal("binding not removed")
+	}
+	if err := bridge.RemoveBinding("test"); err == nil {
+		t.Fatal("expected an error removing a missing binding")
+	}
+}
+
+func TestBindingStopped(t *testing.T) {
+	bus := newTestBus()
+	bridge := NewBridge(bus, nil)
+
+	lamp := &testItem{name: "Lamp", meta: &types.Meta{ValueType: "bool"}}
+	fan := &testItem{name: "Fan", meta: &types.Meta{ValueType: "bool"}}
+	lamps, fans := newTestBinding(lamp), newTestBinding(fan)
+	bridge.AddBinding("lamps", lamps)
+	bridge.AddBinding("fans", fans)
+	lamps.notifications <- types.Notification{Type: types.AddedNotification, Item: lamp}
+	bustest.Receive(t, bus.published)
+	fans.notifications <- types.Notification{Type: types.AddedNotification, Item: fan}
+	bustest.Receive(t, bus.published)
+
+	close(lamps.notifications)
+
+	deadline := time.Now().Add(bustest.Timeout)
+	for bridge.Item("Lamp") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("stopped binding not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if names := bridge.Bindings(); len(names) != 1 || names[0] != "fans" {
+		t.Fatalf("unexpected bindings: %v", names)
+	}
+	if err := bridge.Health(); err != nil {
+		t.Fatalf("expected the bridge to keep running, got %v", err)
+	}
+	if bridge.Item("Fan") == nil || !bus.subscribed("Fan", types.CommandSub) {
+		t.Fatal("expected the other binding's items to stay")
+	}
+}
+
+func TestCommandCoercion(t *testing.T) {
+	bus := newTestBus()
+	bridge := NewBridge(bus, nil)
+
+	label := &testItem{
+		name: "Label",
+		meta: &types.Meta{ValueType: "string"},
+		set:  make(chan types.Value, 1),
+	}
+	lamp := &testItem{
+		name: "Lamp",
+		meta: &types.Meta{ValueType: "bool"},
+		set:  make(chan types.Value, 1),
+	}
+	binding := newTestBinding(label, lamp)
+	bridge.AddBinding("test", binding)
+	for _, item := range []*testItem{label, lamp} {
+		binding.notifications <- types.Notification{Type: types.AddedNotification, Item: item}
+		bustest.Receive(t, bus.published)
+	}
+
+	command := func(name, payload string) {
+		val := new(types.Value)
+		val.FromRaw([]byte(payload))
+		bus.messages <- types.Message{
+			Type:     types.CommandMessage,
+			ItemName: name,
+			Value:    val,
+			Raw:      []byte(payload),
+		}
+	}
+
+	// The payload is parsed as the declared type rather than guessed.
+	command("Label", "1.50")
+	if v := <-label.set; v.Type != types.StringValue {
+		t.Fatalf("expected a string, got %+v", v)
+	} else if s, _ := v.AsString(); s != "1.50" {
+		t.Fatalf("unexpected string: %q", s)
+	}
+
+	command("Label", "2024-05-01")
+	if v := <-label.set; v.Type != types.StringValue {
+		t.Fatalf("expected a string, got %+v", v)
+	}
+
+	command("Lamp", "1")
+	if v := <-lamp.set; v.Type != types.BoolValue {
+		t.Fatalf("expected a bool, got %+v", v)
+	}
+
+	command("Lamp", "maybe")
+	msg := bustest.Receive(t, bus.published)
+	if msg.Type != types.ErrorMessage || msg.ItemName != "Lamp" || msg.Error == "" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	err := bridge.SendCommand(types.Message{ItemName: "Missing", Value: new(types.Value)})
+	if !errors.Is(err, types.ErrNoSuchItem) {
+		t.Fatalf("expected ErrNoSuchItem, got %v", err)
+	}
+}
+
+func TestCommandConstraints(t *testing.T) {
+	bus := newTestBus()
+	bridge := NewBridge(bus, nil)
+
+	max := 100.0
+	dimmer := &testItem{
+		name: "Dimmer",
+		meta: &types.Meta{ValueType: "number", Max: &max},
+		set:  make(chan types.Value, 1),
+	}
+	sensor := &testItem{
+		name: "Sensor",
+		meta: &types.Meta{ValueType: "number", ReadOnly: true},
+		set:  make(chan types.Value, 1),
+	}
+	binding := newTestBinding(dimmer, sensor)
+	bridge.AddBinding("test", binding)
+	for _, item := range []*testItem{dimmer, sensor} {
+		binding.notifications <- types.Notification{Type: types.AddedNotification, Item: item}
+		bustest.Receive(t, bus.published)
+	}
+
+	command := func(name string, n float64) error {
+		val := types.NewNumberValue(n)
+		return bridge.SendCommand(types.Message{Type: types.CommandMessage, ItemName: name, Value: &val})
+	}
+
+	if err := command("Dimmer", 50); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-dimmer.set
+	if err := command("Dimmer", 150); !errors.Is(err, types.ErrInvalidValue) {
+		t.Fatalf("expected ErrInvalidValue, got %v", err)
+	}
+	if err := command("Sensor", 1); !errors.Is(err, types.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestCommandSteps(t *testing.T) {
+	bus := newTestBus()
+	bridge := NewBridge(bus, nil)
+
+	max := 80.0
+	dimmer := &testItem{
+		name:  "Dimmer",
+		meta:  &types.Meta{ValueType: "percent", Step: 10, Max: &max},
+		value: types.NewPercentValue(50),
+		set:   make(chan types.Value, 1),
+	}
+	binding := newTestBinding(dimmer)
+	bridge.AddBinding("test", binding)
+	binding.notifications <- types.Notification{Type: types.AddedNotification, Item: dimmer}
+	bustest.Receive(t, bus.published)
+
+	increase := types.NewEnumValue("INCREASE")
+	// DECREASE arrives as a raw payload, the way MQTT delivers it.
+	var decrease types.Value
+	decrease.FromRaw([]byte("DECREASE"))
+	steps := []struct {
+		command types.Value
+		want    float64
+	}{
+		{increase, 60},
+		{increase, 70},
+		{increase, 80},
+		{increase, 80},
+		{decrease, 70},
+	}
+	for _, s := range steps {
+		cmd := s.command
+		if err := bridge.SendCommand(types.Message{Type: types.CommandMessage, ItemName: "Dimmer", Value: &cmd}); err != nil {
+			t.Fatalf("%+v: unexpected error: %v", s.command, err)
+		}
+		if p, _ := (<-dimmer.set).AsPercent(); p != s.want {
+			t.Fatalf("%+v: expected %v, got %v", s.command, s.want, p)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package broker
+
+import (
+	"log/slog"
+	"net"
+	"os"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+// DefaultListen only accepts local connections, since a broker without a
+// username lets every client command every item.
+const DefaultListen = "127.0.0.1:1883"
+
+// Broker is an MQTT broker running inside the process, so that a single
+// binary is a complete system without a separate mosquitto.
+type Broker struct {
+	cfg    Config
+	server *mochi.Server
+}
+
+func NewBroker(cfg Config) (*Broker, error) {
+	if cfg.Listen == "" {
+		cfg.Listen = DefaultListen
+	}
+
+	server := mochi.New(&mochi.Options{
+		Logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+		})),
+	})
+
+	var err error
+	if cfg.Username == "" {
+		err = server.AddHook(new(auth.AllowHook), nil)
+	} else {
+		err = server.AddHook(new(auth.Hook), &auth.Options{
+			Ledger: &auth.Ledger{
+				Auth: auth.AuthRules{
+					{
+						Username: auth.RString(cfg.Username),
+						Password: auth.RString(cfg.Password),
+						Allow:    true,
+					},
+				},
+			},
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tcp := listeners.NewTCP(listeners.Config{
+		ID:      "tcp",
+		Address: cfg.Listen,
+	})
+	if err := server.AddListener(tcp); err != nil {
+		return nil, err
+	}
+
+	if err := server.Serve(); err != nil {
+		return nil, err
+	}
+
+	return &Broker{
+		cfg:    cfg,
+		server: server,
+	}, nil
+}
+
+// Address returns the host:port local clients should connect to.
+func (b *Broker) Address() string {
+	host, port, err := net.SplitHostPort(b.cfg.Listen)
+	if err != nil {
+		return b.cfg.Listen
+	}
+
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+func (b *Broker) Close() error {
+	return b.server.Close()
+}
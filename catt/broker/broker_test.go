@@ -0,0 +1,55 @@
+package broker
+
+import (
+	"testing"
+
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/mqtt"
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+func TestBroker(t *testing.T) {
+	b, err := NewBroker(Config{
+		Listen:   bustest.FreeAddress(t),
+		Username: "catt",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if _, err := mqtt.NewMqtt(mqtt.Config{Broker: b.Address()}); err == nil {
+		t.Fatal("expected connection without credentials to fail")
+	}
+
+	m, err := mqtt.NewMqtt(mqtt.Config{
+		Broker:   b.Address(),
+		Username: "catt",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Subscribe("Test_Switch", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	val := types.NewBoolValue(true)
+	if err := m.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Test_Switch",
+		Value:    &val,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, m.Messages())
+	if msg.ItemName != "Test_Switch" || msg.Type != types.CommandMessage {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if on, err := msg.Value.AsBool(); err != nil || !on {
+		t.Fatalf("unexpected value: %v", msg.Value)
+	}
+}
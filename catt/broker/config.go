@@ -0,0 +1,11 @@
+package broker
+
+type Config struct {
+	Enabled bool `toml:"enabled"`
+	// Listen is the address the broker accepts connections on. Defaults
+	// to DefaultListen. Other than loopback addresses need a username.
+	Listen string `toml:"listen"`
+	// Username and Password, if set, are required from every client.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
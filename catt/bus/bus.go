@@ -0,0 +1,163 @@
+package bus
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/catt-ha/catt-go/catt/mqtt"
+	"github.com/catt-ha/catt-go/catt/mqtt5"
+	"github.com/catt-ha/catt-go/catt/nats"
+	"github.com/catt-ha/catt-go/catt/redis"
+	"github.com/catt-ha/catt-go/catt/rpc"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/catt-ha/catt-go/catt/ws"
+)
+
+// Config selects a bus backend. The [bus] section holds a type key and the
+// selected backend's own settings, which are decoded into the matching
+// field.
+type Config struct {
+	Type  string
+	Mqtt  mqtt.Config
+	Mqtt5 mqtt5.Config
+	Nats  nats.Config
+	Redis redis.Config
+	Ws    ws.Config
+	Grpc  rpc.Config
+	// WsServer runs the websocket hub in-process and uses it as the bus.
+	WsServer ws.ServerConfig
+}
+
+// Decode decodes the [bus] section. A Type set beforehand, e.g. from the
+// environment, takes precedence over the section's type key.
+func (c *Config) Decode(md *toml.MetaData, section toml.Primitive) error {
+	var selector struct {
+		Type string `toml:"type"`
+	}
+	if err := md.PrimitiveDecode(section, &selector); err != nil {
+		return err
+	}
+
+	if c.Type == "" {
+		c.Type = selector.Type
+	}
+	switch c.Type {
+	case "", "mqtt":
+		c.Type = "mqtt"
+		return md.PrimitiveDecode(section, &c.Mqtt)
+	case "mqtt5":
+		return md.PrimitiveDecode(section, &c.Mqtt5)
+	case "nats":
+		return md.PrimitiveDecode(section, &c.Nats)
+	case "redis":
+		return md.PrimitiveDecode(section, &c.Redis)
+	case "websocket":
+		return md.PrimitiveDecode(section, &c.Ws)
+	case "websocket-server":
+		return md.PrimitiveDecode(section, &c.WsServer)
+	case "grpc":
+		return md.PrimitiveDecode(section, &c.Grpc)
+	default:
+		return fmt.Errorf("invalid bus type: %s", c.Type)
+	}
+}
+
+// Backend returns a pointer to the settings of the selected backend.
+func (c *Config) Backend() interface{} {
+	switch c.Type {
+	case "", "mqtt":
+		return &c.Mqtt
+	case "mqtt5":
+		return &c.Mqtt5
+	case "nats":
+		return &c.Nats
+	case "redis":
+		return &c.Redis
+	case "websocket":
+		return &c.Ws
+	case "websocket-server":
+		return &c.WsServer
+	case "grpc":
+		return &c.Grpc
+	default:
+		return nil
+	}
+}
+
+// SetBroker points an MQTT bus without an explicit broker at the given
+// address, e.g. the embedded broker.
+func (c *Config) SetBroker(address, username, password string) {
+	switch c.Type {
+	case "mqtt":
+		if c.Mqtt.Broker == "" {
+			c.Mqtt.Broker = address
+		}
+		if c.Mqtt.Username == "" {
+			c.Mqtt.Username = username
+			c.Mqtt.Password = password
+		}
+	case "mqtt5":
+		if c.Mqtt5.Broker == "" {
+			c.Mqtt5.Broker = address
+		}
+		if c.Mqtt5.Username == "" {
+			c.Mqtt5.Username = username
+			c.Mqtt5.Password = password
+		}
+	}
+}
+
+// ForClient adapts the bridge's bus settings for a short-lived client like
+// the catt CLI, which must not take over the identity or the state of the
+// bridge: it gets its own client id, doesn't read the Redis command stream
+// and doesn't persist an MQTT publish queue. Bus types that serve rather
+// than connect are refused.
+func (c *Config) ForClient(clientId string) error {
+	switch c.Type {
+	case "", "mqtt":
+		c.Mqtt.ClientId = clientId
+		c.Mqtt.QueueFile = ""
+	case "mqtt5":
+		c.Mqtt5.ClientId = clientId
+	case "nats":
+		c.Nats.ClientId = clientId
+	case "redis":
+		c.Redis.ClientId = clientId
+		c.Redis.Group = ""
+		c.Redis.PublishOnly = true
+	case "websocket-server":
+		return fmt.Errorf("bus type %s serves the bus and can't be used by a client, connect to it with type websocket", c.Type)
+	}
+	return nil
+}
+
+// ForBridge refuses bus types a bridge can't run on. The grpc bus only
+// reads items and sends commands, which is enough for clients like the
+// catt CLI but not for publishing state.
+func (c *Config) ForBridge() error {
+	if c.Type == "grpc" {
+		return fmt.Errorf("bus.type: %s can only be used by clients like the catt CLI", c.Type)
+	}
+	return nil
+}
+
+func (c *Config) New() (types.Bus, error) {
+	switch c.Type {
+	case "", "mqtt":
+		return mqtt.NewMqtt(c.Mqtt)
+	case "mqtt5":
+		return mqtt5.NewMqtt5(c.Mqtt5)
+	case "nats":
+		return nats.NewNats(c.Nats)
+	case "redis":
+		return redis.NewRedis(c.Redis)
+	case "websocket":
+		return ws.NewClient(c.Ws)
+	case "websocket-server":
+		return ws.Listen(c.WsServer)
+	case "grpc":
+		return rpc.NewClient(c.Grpc)
+	default:
+		return nil, fmt.Errorf("invalid bus type: %s", c.Type)
+	}
+}
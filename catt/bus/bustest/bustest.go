@@ -0,0 +1,37 @@
+// Package bustest provides helpers for testing buses and their users.
+package bustest
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+// Timeout is how long Receive waits for a message.
+const Timeout = 5 * time.Second
+
+// Receive returns the next message from ch and fails the test if none
+// arrives within Timeout.
+func Receive(t testing.TB, ch <-chan types.Message) types.Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(Timeout):
+		t.Fatal("timed out waiting for message")
+	}
+	return types.Message{}
+}
+
+// FreeAddress returns a local address nothing is listening on.
+func FreeAddress(t testing.TB) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
@@ -0,0 +1,360 @@
+// Command catt inspects and controls items on a catt bus.
+//
+//	catt list
+//	catt get Light_Switch
+//	catt set Light_Switch ON
+//	catt watch 'Hue_*'
+//	catt meta Light_Switch
+//
+// It reads the [bus] section of the bridge's config file, including the
+// CATT_* environment overrides, and parses values exactly like a bridge
+// parses bus payloads. It connects under its own client id, so it can run
+// next to the bridge.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/config"
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+// item is the JSON form of an item or a message.
+type item struct {
+	Name  string       `json:"name"`
+	Type  string       `json:"type,omitempty"`
+	Value *types.Value `json:"value,omitempty"`
+	Meta  *types.Meta  `json:"meta,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+type options struct {
+	json    bool
+	timeout time.Duration
+	out     io.Writer
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: catt [flags] <command> [args]
+
+commands:
+  list                  list all items with their state
+  get <item>            print the state of an item
+  set <item> <value>    send a command to an item
+  watch [pattern]       print messages as they arrive; pattern may be a glob
+  meta <item>           print the meta of an item
+
+flags:
+`)
+	flag.PrintDefaults()
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "catt: %v\n", err)
+	os.Exit(1)
+}
+
+func main() {
+	cfgPath := flag.String("c", "./config.toml", "path to config file")
+	jsonOut := flag.Bool("json", false, "print JSON instead of tables")
+	timeout := flag.Duration("timeout", 2*time.Second, "how long to wait for items")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	b, err := connect(*cfgPath)
+	if err != nil {
+		fatal(err)
+	}
+	defer closeBus(b)
+
+	opts := options{
+		json:    *jsonOut,
+		timeout: *timeout,
+		out:     os.Stdout,
+	}
+
+	cmd, args := args[0], args[1:]
+	switch {
+	case cmd == "list" && len(args) == 0:
+		err = list(b, opts)
+	case cmd == "get" && len(args) == 1:
+		err = get(b, opts, args[0])
+	case cmd == "set" && len(args) == 2:
+		err = set(b, args[0], args[1])
+	case cmd == "watch" && len(args) <= 1:
+		pattern := "+"
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		err = watch(b, opts, pattern)
+	case cmd == "meta" && len(args) == 1:
+		err = meta(b, opts, args[0])
+	default:
+		usage()
+		closeBus(b)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		closeBus(b)
+		fatal(err)
+	}
+}
+
+func connect(cfgPath string) (types.Bus, error) {
+	cfg, err := config.Load(cfgPath)
+	if os.IsNotExist(errors.Unwrap(err)) {
+		cfg, err = config.FromEnv()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Bus.ForClient(fmt.Sprintf("catt-cli-%d", os.Getpid())); err != nil {
+		return nil, err
+	}
+	return cfg.Bus.New()
+}
+
+func closeBus(b types.Bus) {
+	switch c := b.(type) {
+	case interface{ Close() error }:
+		c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+}
+
+// collect gathers messages until the timeout passes, or until done returns
+// true for the messages seen so far.
+func collect(b types.Bus, timeout time.Duration, done func([]types.Message) bool) []types.Message {
+	var messages []types.Message
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-b.Messages():
+			if !ok {
+				return messages
+			}
+			messages = append(messages, msg)
+			if done != nil && done(messages) {
+				return messages
+			}
+		case <-deadline:
+			return messages
+		}
+	}
+}
+
+// display renders a value on a single line.
+func display(v types.Value) string {
+	if v.Type == types.ColorValue {
+		c, _ := v.AsColor()
+		return fmt.Sprintf("H=%g S=%g V=%g", c.H, c.S, c.V)
+	}
+	s, err := v.AsString()
+	if err != nil {
+		return "<" + err.Error() + ">"
+	}
+	return s
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func list(b types.Bus, opts options) error {
+	if err := b.Subscribe("+", types.UpdateSub); err != nil {
+		return err
+	}
+	if err := b.Subscribe("+", types.MetaSub); err != nil {
+		return err
+	}
+
+	items := make(map[string]*item)
+	for _, msg := range collect(b, opts.timeout, nil) {
+		it, ok := items[msg.ItemName]
+		if !ok {
+			it = &item{Name: msg.ItemName}
+			items[msg.ItemName] = it
+		}
+		switch msg.Type {
+		case types.UpdateMessage:
+			it.Value = msg.Value
+		case types.MetaMessage:
+			it.Meta = msg.Meta
+		}
+	}
+
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if opts.json {
+		out := make([]*item, 0, len(names))
+		for _, name := range names {
+			out = append(out, items[name])
+		}
+		return writeJSON(opts.out, out)
+	}
+
+	w := tabwriter.NewWriter(opts.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tBACKEND\tTYPE\tVALUE")
+	for _, name := range names {
+		it := items[name]
+		backend := ""
+		if it.Meta != nil {
+			backend = it.Meta.Backend
+		}
+		valueType, value := "", ""
+		if it.Value != nil {
+			valueType, value = it.Value.Type.String(), display(*it.Value)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, backend, valueType, value)
+	}
+	return w.Flush()
+}
+
+func get(b types.Bus, opts options, name string) error {
+	if err := b.Subscribe(name, types.UpdateSub); err != nil {
+		return err
+	}
+
+	messages := collect(b, opts.timeout, func(messages []types.Message) bool {
+		return messages[len(messages)-1].ItemName == name
+	})
+
+	for _, msg := range messages {
+		if msg.Type != types.UpdateMessage || msg.ItemName != name {
+			continue
+		}
+
+		if opts.json {
+			return writeJSON(opts.out, &item{Name: name, Value: msg.Value})
+		}
+		_, err := fmt.Fprintln(opts.out, display(*msg.Value))
+		return err
+	}
+
+	return fmt.Errorf("no state received for %s", name)
+}
+
+// set sends the text as given, so that the bridge parses it as the item's
+// declared type rather than a type guessed here.
+func set(b types.Bus, name, raw string) error {
+	val := types.NewStringValue(raw)
+
+	return b.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: name,
+		Value:    &val,
+	})
+}
+
+func meta(b types.Bus, opts options, name string) error {
+	if err := b.Subscribe(name, types.MetaSub); err != nil {
+		return err
+	}
+
+	messages := collect(b, opts.timeout, func(messages []types.Message) bool {
+		return messages[len(messages)-1].ItemName == name
+	})
+
+	for _, msg := range messages {
+		if msg.Type != types.MetaMessage || msg.ItemName != name {
+			continue
+		}
+
+		if opts.json {
+			return writeJSON(opts.out, msg.Meta)
+		}
+		s, err := msg.Meta.AsString()
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(opts.out, s)
+		return err
+	}
+
+	return fmt.Errorf("no meta received for %s", name)
+}
+
+// watch prints messages for items matching pattern until the bus closes. A
+// pattern with glob characters subscribes to every item and filters
+// locally.
+func watch(b types.Bus, opts options, pattern string) error {
+	sub := pattern
+	if strings.ContainsAny(pattern, "*?[") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+		sub = "+"
+	}
+
+	// Not every bus can deliver commands, so fall back to state and meta.
+	if err := b.Subscribe(sub, types.AllSub); err != nil {
+		if err := b.Subscribe(sub, types.UpdateSub); err != nil {
+			return err
+		}
+		if err := b.Subscribe(sub, types.MetaSub); err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(opts.out)
+	for msg := range b.Messages() {
+		if sub == "+" && pattern != "+" {
+			if ok, _ := path.Match(pattern, msg.ItemName); !ok {
+				continue
+			}
+		}
+
+		if opts.json {
+			out := &item{
+				Name:  msg.ItemName,
+				Type:  msg.Type.String(),
+				Value: msg.Value,
+				Meta:  msg.Meta,
+				Error: msg.Error,
+			}
+			if err := enc.Encode(out); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var text string
+		switch msg.Type {
+		case types.MetaMessage:
+			s, _ := msg.Meta.AsString()
+			text = strings.Join(strings.Fields(s), " ")
+		case types.ErrorMessage:
+			text = msg.Error
+		default:
+			text = display(*msg.Value)
+		}
+		fmt.Fprintf(opts.out, "%s %-24s %-7s %s\n", time.Now().Format("15:04:05"), msg.ItemName, msg.Type.String(), text)
+	}
+
+	return errors.New("bus closed")
+}
@@ -2,39 +2,158 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/catt-ha/catt-go/catt"
-	"github.com/catt-ha/catt-go/catt/hue"
-	"github.com/catt-ha/catt-go/catt/mqtt"
-
-	"github.com/BurntSushi/toml"
+	"github.com/catt-ha/catt-go/catt/broker"
+	"github.com/catt-ha/catt-go/catt/config"
+	"github.com/catt-ha/catt-go/catt/health"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
 )
 
-var log = logrus.New()
-
-type Config struct {
-	Bus mqtt.Config `toml:"bus"`
-}
+var log = logging.Logger("main")
 
 func main() {
 	cfgPath := flag.String("c", "./config.toml", "path to config file")
+	embedBroker := flag.Bool("broker", false, "run an embedded mqtt broker")
+	check := flag.Bool("check", false, "validate the config file, print the effective config and exit")
 	flag.Parse()
-	cfg := &Config{}
-	toml.DecodeFile(*cfgPath, cfg)
-	mq, err := mqtt.NewMqtt(cfg.Bus)
+
+	cfg, err := config.Load(*cfgPath)
+	if cfg != nil {
+		if busErr := cfg.Bus.ForBridge(); busErr != nil {
+			errs, _ := err.(config.Errors)
+			err = append(errs, busErr)
+		}
+	}
+
+	if *check {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if cfg != nil {
+			cfg.Write(os.Stdout)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if errs, ok := err.(config.Errors); ok {
+		invalidConfig(errs)
+	} else if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("error loading config")
+	}
+
+	if err := logging.Configure(cfg.Log); err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("invalid log config")
+	}
+
+	// -broker is the same as broker.enabled, which is only valid with an
+	// mqtt or mqtt5 bus.
+	if *embedBroker && !cfg.Broker.Enabled {
+		cfg.Broker.Enabled = true
+		if errs := cfg.Validate(); len(errs) > 0 {
+			invalidConfig(errs)
+		}
+	}
+
+	svc := &services{}
+	busCfg := &cfg.Bus
+
+	if cfg.Broker.Enabled {
+		brk, err := broker.NewBroker(cfg.Broker)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Fatal("error starting embedded broker")
+		}
+		svc.broker = brk
+		busCfg.SetBroker(brk.Address(), cfg.Broker.Username, cfg.Broker.Password)
+	}
+
+	b, err := busCfg.New()
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"error": err,
-		}).Fatal("error starting mqtt connection")
+			"type":  busCfg.Type,
+		}).Error("error starting bus connection")
+		svc.close()
+		os.Exit(1)
+	}
+
+	svc.bridge = catt.NewBridge(b, nil)
+	svc.health = health.New()
+	svc.health.AddLiveness("bridge", svc.bridge)
+	svc.health.AddReadiness("hue", health.CheckFunc(svc.hueHealth))
+	if checker, ok := b.(health.Checker); ok {
+		svc.health.AddReadiness("bus", checker)
+	}
+
+	if err := svc.start(cfg); err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("error starting services")
+		svc.close()
+		closeBus(b)
+		os.Exit(1)
 	}
-	h, err := hue.NewHue()
+	defer svc.close()
+
+	stop, err := config.Watch(*cfgPath, svc.reload)
 	if err != nil {
 		log.WithFields(logrus.Fields{
 			"error": err,
-		}).Fatal("error starting hue connection")
+		}).Warn("error watching the config file, reloading is disabled")
+		// Don't let a reload request from the service manager kill us.
+		signal.Ignore(syscall.SIGHUP)
+	} else {
+		defer stop()
 	}
-	br := catt.NewBridge(mq, h)
 
-	br.Run()
+	// Closing the bus stops the bridge, so Run returns and the deferred
+	// cleanup, including the embedded broker, runs.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.WithFields(logrus.Fields{
+			"signal": sig,
+		}).Info("shutting down")
+		closeBus(b)
+	}()
+
+	svc.health.Systemd()
+
+	svc.bridge.Run()
+}
+
+// closeBus closes a bus if it supports being closed.
+func closeBus(b types.Bus) {
+	switch c := b.(type) {
+	case interface{ Close() error }:
+		c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+}
+
+// invalidConfig logs every config error and exits.
+func invalidConfig(errs config.Errors) {
+	for _, e := range errs {
+		log.WithFields(logrus.Fields{
+			"error": e,
+		}).Error("invalid config")
+	}
+	log.Fatal("invalid config, run with -check for details")
 }
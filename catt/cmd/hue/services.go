@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt"
+	"github.com/catt-ha/catt-go/catt/broker"
+	"github.com/catt-ha/catt-go/catt/config"
+	"github.com/catt-ha/catt-go/catt/health"
+	"github.com/catt-ha/catt-go/catt/httpapi"
+	"github.com/catt-ha/catt-go/catt/hue"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/metrics"
+	"github.com/catt-ha/catt-go/catt/rpc"
+	"google.golang.org/grpc"
+)
+
+const hueBinding = "hue"
+
+// services holds the components that are started from the config and
+// restarted when it changes. The bus and the embedded broker carry the
+// bridge's subscriptions and are only set up once.
+type services struct {
+	bridge *catt.Bridge
+	health *health.Health
+	broker *broker.Broker
+
+	// reloading serializes starting, reloading and closing. mu guards cfg
+	// and hue, which health checks read, and is only held briefly so that
+	// a slow restart doesn't stall them.
+	reloading  sync.Mutex
+	mu         sync.Mutex
+	cfg        *config.Config
+	hue        *hue.Hue
+	grpc       *grpc.Server
+	http       *http.Server
+	metrics    *http.Server
+	healthHttp *http.Server
+}
+
+// start starts every component enabled in cfg.
+func (s *services) start(cfg *config.Config) error {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+
+	s.setConfig(cfg)
+	for _, section := range []string{"hue", "grpc", "http", "metrics", "health"} {
+		if err := s.restart(section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *services) setConfig(cfg *config.Config) {
+	s.mu.Lock()
+	s.cfg = cfg
+	s.mu.Unlock()
+}
+
+// restart stops the component configured by a section and starts it again
+// with the current config, if enabled. Must be called with reloading held.
+func (s *services) restart(section string) error {
+	var err error
+	switch section {
+	case "log":
+		err = logging.Configure(s.cfg.Log)
+	case "hue":
+		s.mu.Lock()
+		old := s.hue
+		s.hue = nil
+		s.mu.Unlock()
+		if old != nil {
+			s.bridge.RemoveBinding(hueBinding)
+		}
+		if s.cfg.Hue.Disabled {
+			return nil
+		}
+		// Discovery and pairing can take long, so the binding is built
+		// without holding mu.
+		var h *hue.Hue
+		if h, err = hue.NewHue(s.cfg.Hue); err == nil {
+			s.mu.Lock()
+			s.hue = h
+			s.mu.Unlock()
+			err = s.bridge.AddBinding(hueBinding, h)
+		}
+	case "grpc":
+		if s.grpc != nil {
+			s.grpc.Stop()
+			s.grpc = nil
+		}
+		if s.cfg.Grpc.Listen != "" {
+			s.grpc, err = rpc.Listen(s.cfg.Grpc, s.bridge)
+		}
+	case "http":
+		closeServer(&s.http)
+		if s.cfg.Http.Listen != "" {
+			s.http, err = httpapi.Listen(s.cfg.Http, s.bridge)
+		}
+	case "metrics":
+		closeServer(&s.metrics)
+		if s.cfg.Metrics.Listen != "" {
+			s.metrics, err = metrics.Listen(s.cfg.Metrics, s.bridge)
+		}
+	case "health":
+		closeServer(&s.healthHttp)
+		if s.cfg.Health.Listen != "" {
+			s.healthHttp, err = s.health.Listen(s.cfg.Health)
+		}
+	}
+	return err
+}
+
+func closeServer(srv **http.Server) {
+	if *srv != nil {
+		(*srv).Close()
+		*srv = nil
+	}
+}
+
+// reload applies a new config, restarting only the components whose
+// sections changed. An invalid config is rejected as a whole.
+func (s *services) reload(cfg *config.Config, err error) {
+	if err != nil {
+		if errs, ok := err.(config.Errors); ok {
+			for _, e := range errs {
+				log.WithFields(logrus.Fields{
+					"error": e,
+				}).Error("invalid config")
+			}
+		} else {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("error reloading config")
+		}
+		log.Error("keeping the current config")
+		return
+	}
+
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+
+	if s.broker != nil {
+		cfg.Bus.SetBroker(s.broker.Address(), cfg.Broker.Username, cfg.Broker.Password)
+	}
+
+	changed := config.Changed(s.cfg, cfg)
+	if len(changed) == 0 {
+		log.Debug("config unchanged")
+		return
+	}
+
+	old := s.cfg
+	s.setConfig(cfg)
+	for _, section := range changed {
+		fields := logrus.Fields{
+			"section": section,
+		}
+		switch section {
+		case "bus", "broker":
+			log.WithFields(fields).Warn("changes to this section take effect after a restart")
+			cfg.Bus = old.Bus
+			cfg.Broker = old.Broker
+			continue
+		}
+		if err := s.restart(section); err != nil {
+			fields["error"] = err
+			log.WithFields(fields).Error("error applying config")
+			continue
+		}
+		log.WithFields(fields).Info("config reloaded")
+	}
+}
+
+// hueHealth checks the current Hue binding, if enabled.
+func (s *services) hueHealth() error {
+	s.mu.Lock()
+	h, disabled := s.hue, s.cfg.Hue.Disabled
+	s.mu.Unlock()
+
+	if disabled {
+		return nil
+	}
+	if h == nil {
+		return errors.New("hue binding not running")
+	}
+	return h.Health()
+}
+
+// close stops every component, the embedded broker last.
+func (s *services) close() {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+
+	if s.grpc != nil {
+		s.grpc.Stop()
+	}
+	closeServer(&s.http)
+	closeServer(&s.metrics)
+	closeServer(&s.healthHttp)
+	if s.broker != nil {
+		s.broker.Close()
+		s.broker = nil
+	}
+}
@@ -0,0 +1,160 @@
+// Package config loads and validates the configuration file of a bridge.
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/catt-ha/catt-go/catt/broker"
+	"github.com/catt-ha/catt-go/catt/bus"
+	"github.com/catt-ha/catt-go/catt/health"
+	"github.com/catt-ha/catt-go/catt/httpapi"
+	"github.com/catt-ha/catt-go/catt/hue"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/metrics"
+	"github.com/catt-ha/catt-go/catt/rpc"
+)
+
+type Config struct {
+	// BusSection holds the raw [bus] section, which is decoded into Bus
+	// according to its type key.
+	BusSection toml.Primitive `toml:"bus"`
+	Bus        bus.Config     `toml:"-"`
+
+	Hue     hue.Config           `toml:"hue"`
+	Broker  broker.Config        `toml:"broker"`
+	Grpc    rpc.ServerConfig     `toml:"grpc"`
+	Http    httpapi.ServerConfig `toml:"http"`
+	Metrics metrics.Config       `toml:"metrics"`
+	Health  health.Config        `toml:"health"`
+	Log     logging.Config       `toml:"log"`
+
+	sources map[string]string
+}
+
+// Errors collects every problem found in a config file.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+type loader struct {
+	md      toml.MetaData
+	raw     map[string]interface{}
+	handled map[string]bool
+	sources map[string]string
+	errs    Errors
+}
+
+// Load reads and validates a config file and applies the overrides from the
+// environment. Syntax errors and unreadable files are returned as is;
+// otherwise the config is returned along with an Errors value listing every
+// problem found, if any.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	cfg, err := parse(string(data))
+	if _, ok := err.(Errors); !ok && err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return cfg, err
+}
+
+// FromEnv builds a config from the defaults and the environment alone, for
+// tools that can run without a config file.
+func FromEnv() (*Config, error) {
+	return parse("")
+}
+
+func parse(data string) (*Config, error) {
+	cfg := &Config{}
+	md, err := toml.Decode(data, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{
+		md:      md,
+		raw:     make(map[string]interface{}),
+		handled: make(map[string]bool),
+		sources: make(map[string]string),
+	}
+	if _, err := toml.Decode(data, &l.raw); err != nil {
+		return nil, err
+	}
+
+	typeEnv := envName("bus", "type")
+	l.sources["bus.type"] = SourceDefault
+	if md.IsDefined("bus", "type") {
+		l.sources["bus.type"] = SourceFile
+	}
+	if t, ok := os.LookupEnv(typeEnv); ok {
+		cfg.Bus.Type = t
+		l.sources["bus.type"] = "env " + typeEnv
+	}
+
+	if err := cfg.Bus.Decode(&md, cfg.BusSection); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("bus: %v", err))
+	}
+
+	for _, s := range cfg.sections() {
+		if s.ptr != nil {
+			l.override(s.name, s.ptr)
+		}
+	}
+	cfg.sources = l.sources
+
+	var unknown []string
+	for _, key := range md.Undecoded() {
+		if !l.handled[key.String()] {
+			unknown = append(unknown, key.String())
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		l.errs = append(l.errs, fmt.Errorf("unknown key %s", key))
+	}
+
+	errs := append(l.errs, cfg.Validate()...)
+
+	if len(errs) > 0 {
+		return cfg, errs
+	}
+	return cfg, nil
+}
+
+type section struct {
+	name string
+	ptr  interface{}
+}
+
+// sections lists the config sections in the order they are printed.
+func (c *Config) sections() []section {
+	return []section{
+		{"bus", c.Bus.Backend()},
+		{"hue", &c.Hue},
+		{"broker", &c.Broker},
+		{"grpc", &c.Grpc},
+		{"http", &c.Http},
+		{"metrics", &c.Metrics},
+		{"health", &c.Health},
+		{"log", &c.Log},
+	}
+}
+
+// Sources reports where each setting came from: SourceDefault, SourceFile,
+// "env <VARIABLE>" or "file <path>", keyed by section.key.
+func (c *Config) Sources() map[string]string {
+	return c.sources
+}
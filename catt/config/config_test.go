@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func write(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load(write(t, `
+[bus]
+broker = "10.8.0.1:1883"
+username = "catt"
+password = "secret"
+
+[hue]
+username = "hue-api-key"
+
+[http]
+listen = ":8080"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Bus.Type != "mqtt" || cfg.Bus.Mqtt.Broker != "10.8.0.1:1883" {
+		t.Fatalf("unexpected bus config: %+v", cfg.Bus)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cfg.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "hue-api-key") || !strings.Contains(buf.String(), `type = "mqtt"`) {
+		t.Fatalf("unexpected effective config:\n%s", buf)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Fatal("expected a missing file to fail")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load(write(t, `
+[bus]
+brokr = "10.8.0.1:1883"
+broker = "tcp://10.8.0.1:1883"
+password = "secret"
+overflow = "sometimes"
+
+[http]
+listen = "localhost"
+
+[log]
+level = "chatty"
+`))
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	expected := []string{
+		"unknown key bus.brokr",
+		"bus.broker:",
+		"bus: password set without a username",
+		"bus.overflow:",
+		"http.listen:",
+		"log:",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got:\n%v", len(expected), errs)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("expected error %d to start with %q, got %q", i, prefix, errs[i])
+		}
+	}
+}
+
+func TestLoadRequired(t *testing.T) {
+	_, err := Load(write(t, `
+[bus]
+type = "websocket"
+`))
+	if err == nil || !strings.Contains(err.Error(), "bus.broker: required") {
+		t.Fatalf("expected missing broker error, got %v", err)
+	}
+}
+
+func TestLoadExposed(t *testing.T) {
+	for listen, ok := range map[string]bool{
+		"127.0.0.1:1883": true,
+		"localhost:1883": true,
+		"[::1]:1883":     true,
+		":1883":          false,
+		"0.0.0.0:1883":   false,
+		"10.8.0.1:1883":  false,
+	} {
+		// The grpc server is enabled by listen alone.
+		for section, enable := range map[string]string{"broker": "enabled = true", "grpc": ""} {
+			_, err := Load(write(t, `
+[`+section+`]
+`+enable+`
+listen = "`+listen+`"
+`))
+			if ok && err != nil {
+				t.Errorf("%s %q: unexpected error %v", section, listen, err)
+			}
+			if !ok && (err == nil || !strings.Contains(err.Error(), section+".listen:")) {
+				t.Errorf("%s %q: expected %s.listen error, got %v", section, listen, section, err)
+			}
+
+			if _, err := Load(write(t, `
+[`+section+`]
+`+enable+`
+listen = "`+listen+`"
+username = "catt"
+password = "secret"
+`)); err != nil {
+				t.Errorf("%s %q with a username: unexpected error %v", section, listen, err)
+			}
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+	if err := os.WriteFile(secret, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	username := filepath.Join(dir, "username")
+	if err := os.WriteFile(username, []byte("hue-user\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("CATT_BUS_TYPE", "redis")
+	t.Setenv("CATT_BUS_BROKER", "10.8.0.2:6379")
+	t.Setenv("CATT_BUS_PASSWORD_FILE", secret)
+	t.Setenv("CATT_BUS_DB", "3")
+	t.Setenv("CATT_BUS_BUFFER", "64")
+	t.Setenv("CATT_HUE_POLL_INTERVAL", "10")
+	t.Setenv("CATT_LOG_COMPONENTS_MQTT", "debug")
+
+	cfg, err := Load(write(t, `
+[bus]
+type = "mqtt"
+username = "catt"
+password = "committed"
+
+[hue]
+address = "10.8.0.3"
+username_file = "`+username+`"
+
+[log.components]
+hue = "warn"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Bus.Type != "redis" {
+		t.Fatalf("expected env to select the bus type, got %s", cfg.Bus.Type)
+	}
+	r := cfg.Bus.Redis
+	if r.Broker != "10.8.0.2:6379" || r.Username != "catt" || r.Password != "from-file" || r.Db != 3 || r.Buffer != 64 {
+		t.Fatalf("unexpected redis config: %+v", r)
+	}
+	if cfg.Hue.Username != "hue-user" || cfg.Hue.PollInterval != 10 {
+		t.Fatalf("unexpected hue config: %+v", cfg.Hue)
+	}
+	if cfg.Log.Components["mqtt"] != "debug" || cfg.Log.Components["hue"] != "warn" {
+		t.Fatalf("unexpected log config: %+v", cfg.Log)
+	}
+
+	sources := cfg.Sources()
+	expected := map[string]string{
+		"bus.type":            "env CATT_BUS_TYPE",
+		"bus.broker":          "env CATT_BUS_BROKER",
+		"bus.username":        SourceFile,
+		"bus.password":        "file " + secret + " via CATT_BUS_PASSWORD_FILE",
+		"bus.tls":             SourceDefault,
+		"bus.buffer":          "env CATT_BUS_BUFFER",
+		"hue.username":        "file " + username,
+		"hue.poll_interval":   "env CATT_HUE_POLL_INTERVAL",
+		"log.components.mqtt": "env CATT_LOG_COMPONENTS_MQTT",
+	}
+	for key, source := range expected {
+		if sources[key] != source {
+			t.Errorf("expected source of %s to be %q, got %q", key, source, sources[key])
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cfg.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `broker = "10.8.0.2:6379"  # env CATT_BUS_BROKER`) {
+		t.Fatalf("expected sources in the effective config:\n%s", buf)
+	}
+}
+
+func TestOverrideErrors(t *testing.T) {
+	t.Setenv("CATT_BUS_TLS", "maybe")
+	t.Setenv("CATT_BUS_PASSWORD", "secret")
+	t.Setenv("CATT_BUS_PASSWORD_FILE", "/nonexistent")
+
+	_, err := Load(write(t, `
+[bus]
+username = "catt"
+`))
+	if err == nil || !strings.Contains(err.Error(), "CATT_BUS_TLS") || !strings.Contains(err.Error(), "both CATT_BUS_PASSWORD and CATT_BUS_PASSWORD_FILE") {
+		t.Fatalf("expected override errors, got %v", err)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	old, err := Load(write(t, `
+[bus]
+username = "catt"
+password = "old"
+
+[log]
+level = "info"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := Load(write(t, `
+[bus]
+username = "catt"
+password = "new"
+
+[log]
+level = "debug"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := Changed(old, old); len(changed) != 0 {
+		t.Fatalf("unexpected changes: %v", changed)
+	}
+	if changed := strings.Join(Changed(old, new), ","); changed != "bus,log" {
+		t.Fatalf("unexpected changes: %s", changed)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := write(t, "[log]\nlevel = \"info\"\n")
+
+	loaded := make(chan *Config, 1)
+	stop, err := Watch(path, func(cfg *Config, err error) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		loaded <- cfg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	if err := os.WriteFile(path, []byte("[log]\nlevel = \"debug\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-loaded:
+		if cfg.Log.Level != "debug" {
+			t.Fatalf("unexpected log level: %s", cfg.Log.Level)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded")
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of environment variables overriding config
+// keys. The key password in the [bus] section is overridden by
+// CATT_BUS_PASSWORD, and the entry mqtt of the table [log.components] by
+// CATT_LOG_COMPONENTS_MQTT.
+const EnvPrefix = "CATT_"
+
+// Sources of a setting, as reported by Sources.
+const (
+	SourceDefault = "default"
+	SourceFile    = "config file"
+)
+
+// envName returns the environment variable overriding a key.
+func envName(section, key string) string {
+	name := EnvPrefix + section + "_" + key
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// readSecret reads a value from a file, without a trailing newline.
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// setField parses s into a field of a config struct.
+func setField(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// override applies the overrides for one section, given as a pointer to its
+// struct, and records the source of every key.
+//
+// A key can be set, in increasing order of precedence, by the config file,
+// by a <key>_file entry in the config file naming a file to read the value
+// from, by a CATT_<SECTION>_<KEY> environment variable or by a
+// CATT_<SECTION>_<KEY>_FILE environment variable naming a file. Files can
+// only be used for string keys. Entries of tables are set by
+// CATT_<SECTION>_<KEY>_<ENTRY> variables, with the entry in lower case.
+func (l *loader) override(section string, ptr interface{}) {
+	v := reflect.ValueOf(ptr).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		// Embedded structs, like delivery.Config, contribute their keys to
+		// the section.
+		if t.Field(i).Anonymous && t.Field(i).Type.Kind() == reflect.Struct {
+			l.override(section, v.Field(i).Addr().Interface())
+			continue
+		}
+
+		key := strings.Split(t.Field(i).Tag.Get("toml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		if t.Field(i).Type.Kind() == reflect.Map {
+			l.overrideMap(section+"."+key, envName(section, key), v.Field(i))
+			continue
+		}
+
+		field := v.Field(i)
+		path := section + "." + key
+		isString := field.Kind() == reflect.String
+
+		source := SourceDefault
+		if l.md.IsDefined(section, key) {
+			source = SourceFile
+		}
+
+		fileKey := key + "_file"
+		if l.md.IsDefined(section, fileKey) {
+			l.handled[section+"."+fileKey] = true
+			raw, _ := l.raw[section].(map[string]interface{})
+			file, ok := raw[fileKey].(string)
+			switch {
+			case !isString:
+				l.errs = append(l.errs, fmt.Errorf("%s: %s only works for string keys", path, fileKey))
+			case !ok:
+				l.errs = append(l.errs, fmt.Errorf("%s: %s must be a path", path, fileKey))
+			default:
+				if secret, err := readSecret(file); err != nil {
+					l.errs = append(l.errs, fmt.Errorf("%s: %v", path, err))
+				} else {
+					field.SetString(secret)
+					source = "file " + file
+				}
+			}
+		}
+
+		env := envName(section, key)
+		value, inEnv := os.LookupEnv(env)
+		file, fileInEnv := os.LookupEnv(env + "_FILE")
+		switch {
+		case inEnv && fileInEnv:
+			l.errs = append(l.errs, fmt.Errorf("%s: both %s and %s_FILE are set", path, env, env))
+		case inEnv:
+			if err := setField(field, value); err != nil {
+				l.errs = append(l.errs, fmt.Errorf("%s: %s: %v", path, env, err))
+			} else {
+				source = "env " + env
+			}
+		case fileInEnv && !isString:
+			l.errs = append(l.errs, fmt.Errorf("%s: %s_FILE only works for string keys", path, env))
+		case fileInEnv:
+			if secret, err := readSecret(file); err != nil {
+				l.errs = append(l.errs, fmt.Errorf("%s: %s_FILE: %v", path, env, err))
+			} else {
+				field.SetString(secret)
+				source = "file " + file + " via " + env + "_FILE"
+			}
+		}
+
+		l.sources[path] = source
+	}
+}
+
+// overrideMap sets the entries of a table from the environment variables
+// starting with prefix_.
+func (l *loader) overrideMap(path, prefix string, field reflect.Value) {
+	if field.Type().Key().Kind() != reflect.String {
+		return
+	}
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix+"_") || len(name) == len(prefix)+1 {
+			continue
+		}
+		entry := strings.ToLower(strings.TrimPrefix(name, prefix+"_"))
+
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if err := setField(elem, value); err != nil {
+			l.errs = append(l.errs, fmt.Errorf("%s.%s: %s: %v", path, entry, name, err))
+			continue
+		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		field.SetMapIndex(reflect.ValueOf(entry).Convert(field.Type().Key()), elem)
+		l.sources[path+"."+entry] = "env " + name
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const redacted = "<redacted>"
+
+// toMap converts a config struct to its TOML key/value form.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	_, err := toml.Decode(buf.String(), &m)
+	return m, err
+}
+
+// secrets lists the keys, as section.key, whose values are hidden besides
+// the password keys of every section. The Hue username is the API key of
+// the bridge.
+var secrets = map[string]bool{
+	"hue.username": true,
+}
+
+// redact hides the values of secret keys in the table at path.
+func redact(path string, m map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if path != "" {
+			key = path + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			redact(key, v)
+		case string:
+			if (k == "password" || secrets[key]) && v != "" {
+				m[k] = redacted
+			}
+		}
+	}
+}
+
+// Effective returns the decoded configuration as TOML sections, with the
+// [bus] section reduced to the selected backend and secrets redacted.
+func (c *Config) Effective() (map[string]interface{}, error) {
+	out, err := c.tables()
+	if err != nil {
+		return nil, err
+	}
+
+	redact("", out)
+	return out, nil
+}
+
+// tables returns the decoded configuration as TOML sections.
+func (c *Config) tables() (map[string]interface{}, error) {
+	out := make(map[string]interface{})
+
+	for _, s := range c.sections() {
+		if s.ptr == nil {
+			continue
+		}
+		m, err := toMap(s.ptr)
+		if err != nil {
+			return nil, err
+		}
+		out[s.name] = m
+	}
+
+	if bus, ok := out["bus"].(map[string]interface{}); ok {
+		bus["type"] = c.Bus.Type
+	}
+
+	return out, nil
+}
+
+// formatValue renders a single TOML value.
+func formatValue(v interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(map[string]interface{}{"v": v}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(buf.String(), "v = ")), nil
+}
+
+func writeTable(w io.Writer, name string, table map[string]interface{}, sources map[string]string) error {
+	fmt.Fprintf(w, "[%s]\n", name)
+
+	keys := make([]string, 0, len(table))
+	var subtables []string
+	for k, v := range table {
+		if _, ok := v.(map[string]interface{}); ok {
+			subtables = append(subtables, k)
+		} else {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	sort.Strings(subtables)
+
+	for _, k := range keys {
+		value, err := formatValue(table[k])
+		if err != nil {
+			return err
+		}
+		line := fmt.Sprintf("%s = %s", k, value)
+		if source := sources[name+"."+k]; source != "" && source != SourceDefault {
+			line += "  # " + source
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	for _, k := range subtables {
+		fmt.Fprintln(w)
+		if err := writeTable(w, name+"."+k, table[k].(map[string]interface{}), sources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write prints the effective configuration as TOML, annotating every
+// setting that doesn't have its default value with its source.
+func (c *Config) Write(w io.Writer) error {
+	out, err := c.Effective()
+	if err != nil {
+		return err
+	}
+
+	for i, s := range c.sections() {
+		table, ok := out[s.name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := writeTable(w, s.name, table, c.sources); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
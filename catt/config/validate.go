@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/logging"
+)
+
+// validator accumulates errors under a section prefix.
+type validator struct {
+	errs Errors
+}
+
+func (v *validator) add(section, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", section, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) check(section string, err error) {
+	if err != nil {
+		v.add(section, "%v", err)
+	}
+}
+
+// checkHostPort validates an address of the form host:port. The host may be
+// empty to mean all interfaces.
+func checkHostPort(addr string) error {
+	if strings.Contains(addr, "://") {
+		return fmt.Errorf("%q must be host:port, without a scheme", addr)
+	}
+
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", addr, err)
+	}
+
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 && port != "0" {
+		return fmt.Errorf("invalid port in %q", addr)
+	}
+	return nil
+}
+
+// checkURL validates a URL with one of the given schemes.
+func checkURL(s string, schemes ...string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %v", s, err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid url %q: missing host", s)
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid url %q: scheme must be one of %s", s, strings.Join(schemes, ", "))
+}
+
+func (v *validator) broker(section, broker string) {
+	if broker != "" {
+		v.check(section+".broker", checkHostPort(broker))
+	}
+}
+
+func (v *validator) listen(section, listen string) {
+	if listen != "" {
+		v.check(section+".listen", checkHostPort(listen))
+	}
+}
+
+// loopback reports whether a listen address only accepts local
+// connections.
+func loopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// exposed refuses a server reachable from the network that doesn't ask for
+// a username.
+func (v *validator) exposed(section, listen, username string) {
+	if listen != "" && username == "" && !loopback(listen) {
+		v.add(section+".listen", "%s is reachable from the network and needs a username", listen)
+	}
+}
+
+func (v *validator) credentials(section, username, password string) {
+	if username == "" && password != "" {
+		v.add(section, "password set without a username")
+	}
+}
+
+func (v *validator) delivery(section string, buffer int, overflow string) {
+	if buffer < 0 {
+		v.add(section+".buffer", "must not be negative")
+	}
+	_, err := delivery.ParsePolicy(overflow)
+	v.check(section+".overflow", err)
+}
+
+// Validate reports every invalid or missing setting.
+func (c *Config) Validate() Errors {
+	v := &validator{}
+
+	b := c.Bus
+	switch b.Type {
+	case "mqtt":
+		v.broker("bus", b.Mqtt.Broker)
+		v.credentials("bus", b.Mqtt.Username, b.Mqtt.Password)
+		v.delivery("bus", b.Mqtt.Buffer, b.Mqtt.Overflow)
+		if b.Mqtt.QueueSize < 0 {
+			v.add("bus.queue_size", "must not be negative")
+		}
+	case "mqtt5":
+		v.broker("bus", b.Mqtt5.Broker)
+		v.credentials("bus", b.Mqtt5.Username, b.Mqtt5.Password)
+		v.delivery("bus", b.Mqtt5.Buffer, b.Mqtt5.Overflow)
+	case "nats":
+		if strings.Contains(b.Nats.Broker, "://") {
+			v.check("bus.broker", checkURL(b.Nats.Broker, "nats", "tls"))
+		} else {
+			v.broker("bus", b.Nats.Broker)
+		}
+		v.credentials("bus", b.Nats.Username, b.Nats.Password)
+		v.delivery("bus", b.Nats.Buffer, b.Nats.Overflow)
+	case "redis":
+		v.broker("bus", b.Redis.Broker)
+		v.credentials("bus", b.Redis.Username, b.Redis.Password)
+		v.delivery("bus", b.Redis.Buffer, b.Redis.Overflow)
+		if b.Redis.StreamLength < 0 {
+			v.add("bus.stream_length", "must not be negative")
+		}
+		if b.Redis.PendingTimeout < 0 {
+			v.add("bus.pending_timeout", "must not be negative")
+		}
+	case "websocket":
+		if b.Ws.Broker == "" {
+			v.add("bus.broker", "required for the websocket bus")
+		} else {
+			v.check("bus.broker", checkURL(b.Ws.Broker, "ws", "wss"))
+		}
+		v.credentials("bus", b.Ws.Username, b.Ws.Password)
+		v.delivery("bus", b.Ws.Buffer, b.Ws.Overflow)
+	case "websocket-server":
+		v.listen("bus", b.WsServer.Listen)
+		v.credentials("bus", b.WsServer.Username, b.WsServer.Password)
+		v.delivery("bus", b.WsServer.Buffer, b.WsServer.Overflow)
+	case "grpc":
+		if b.Grpc.Broker == "" {
+			v.add("bus.broker", "required for the grpc bus")
+		} else if !strings.Contains(b.Grpc.Broker, "://") {
+			v.broker("bus", b.Grpc.Broker)
+		}
+		v.credentials("bus", b.Grpc.Username, b.Grpc.Password)
+		v.delivery("bus", b.Grpc.Buffer, b.Grpc.Overflow)
+	}
+
+	if c.Hue.PollInterval < 0 {
+		v.add("hue.poll_interval", "must not be negative")
+	}
+
+	if c.Broker.Enabled {
+		v.listen("broker", c.Broker.Listen)
+		v.credentials("broker", c.Broker.Username, c.Broker.Password)
+		v.exposed("broker", c.Broker.Listen, c.Broker.Username)
+		if b.Type != "mqtt" && b.Type != "mqtt5" {
+			v.add("broker", "the embedded broker needs an mqtt or mqtt5 bus")
+		}
+	}
+
+	v.listen("grpc", c.Grpc.Listen)
+	v.credentials("grpc", c.Grpc.Username, c.Grpc.Password)
+	if (c.Grpc.TlsCert == "") != (c.Grpc.TlsKey == "") {
+		v.add("grpc", "tls_cert and tls_key must be set together")
+	}
+	v.exposed("grpc", c.Grpc.Listen, c.Grpc.Username)
+	v.listen("http", c.Http.Listen)
+	v.credentials("http", c.Http.Username, c.Http.Password)
+	v.listen("metrics", c.Metrics.Listen)
+	v.listen("health", c.Health.Listen)
+
+	if c.Metrics.Items && c.Metrics.Listen == "" {
+		v.add("metrics.items", "set without metrics.listen")
+	}
+	if c.Http.Dashboard && c.Http.Listen == "" {
+		v.add("http.dashboard", "set without http.listen")
+	}
+
+	v.check("log", logging.Validate(c.Log))
+
+	return v.errs
+}
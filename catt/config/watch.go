@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay collapses the bursts of events editors produce when saving.
+const reloadDelay = 500 * time.Millisecond
+
+// Changed returns the names of the sections that differ between two
+// configs, in the order they are printed.
+func Changed(old, new *Config) []string {
+	oldTables, oldErr := old.tables()
+	newTables, newErr := new.tables()
+
+	var changed []string
+	for _, s := range new.sections() {
+		if oldErr != nil || newErr != nil || !reflect.DeepEqual(oldTables[s.name], newTables[s.name]) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
+// Watch loads the config file again whenever it changes on disk or the
+// process receives SIGHUP, and passes the result of Load to fn. The
+// directory of the file is watched, so that editors replacing the file
+// are noticed too. Calling the returned function stops watching.
+func Watch(path string, fn func(*Config, error)) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hup)
+
+		name := filepath.Clean(path)
+		var pending <-chan time.Time
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != name || ev.Op == fsnotify.Chmod {
+					continue
+				}
+				pending = time.After(reloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fn(nil, err)
+			case <-hup:
+				pending = nil
+				fn(Load(path))
+			case <-pending:
+				pending = nil
+				fn(Load(path))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }, nil
+}
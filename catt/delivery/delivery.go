@@ -0,0 +1,184 @@
+package delivery
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultSize = 256
+
+// Policy decides what happens when a message is pushed onto a full queue.
+type Policy uint8
+
+const (
+	// Block waits until the consumer makes room.
+	Block Policy = iota
+	// DropOldest evicts the oldest pending message.
+	DropOldest
+	// Coalesce replaces the pending message for the same item if it is of
+	// the same type, and evicts the oldest pending message otherwise.
+	Coalesce
+)
+
+func (p Policy) String() string {
+	switch p {
+	case Block:
+		return "block"
+	case DropOldest:
+		return "drop-oldest"
+	case Coalesce:
+		return "coalesce"
+	}
+	return "???"
+}
+
+func ParsePolicy(s string) (Policy, error) {
+	switch strings.ToLower(s) {
+	case "", "block":
+		return Block, nil
+	case "drop-oldest":
+		return DropOldest, nil
+	case "coalesce":
+		return Coalesce, nil
+	}
+	return 0, fmt.Errorf("invalid overflow policy: %s", s)
+}
+
+// Config sizes the queue of received messages. Bus configs embed it, so
+// its keys sit directly in the bus section.
+type Config struct {
+	// Buffer bounds the number of received messages waiting to be
+	// consumed. Defaults to DefaultSize.
+	Buffer int `toml:"buffer"`
+	// Overflow is applied when the buffer is full: "block" (default),
+	// "drop-oldest" or "coalesce".
+	Overflow string `toml:"overflow"`
+}
+
+// Queue is a bounded FIFO between a bus client's receive callback and the
+// consumer of types.Bus.Messages. Messages are delivered in the order they
+// were pushed, so commands for an item can never overtake each other.
+type Queue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	size    int
+	policy  Policy
+	pending []types.Message
+	out     chan types.Message
+	dropped uint64
+	closed  bool
+
+	droppedTotal prometheus.Counter
+	depth        prometheus.Gauge
+}
+
+// NewQueue creates the queue of received messages of the named bus.
+func NewQueue(bus string, size int, policy Policy) *Queue {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	q := &Queue{
+		size:         size,
+		policy:       policy,
+		out:          make(chan types.Message),
+		droppedTotal: DroppedMessages.WithLabelValues(bus, "receive"),
+		depth:        QueueDepth.WithLabelValues(bus, "receive"),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	go q.pump()
+
+	return q
+}
+
+func (q *Queue) pump() {
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			close(q.out)
+			return
+		}
+		msg := q.pending[0]
+		q.pending = q.pending[1:]
+		q.depth.Dec()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+
+		q.out <- msg
+	}
+}
+
+func (q *Queue) Push(msg types.Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.policy == Block {
+		for len(q.pending) >= q.size && !q.closed {
+			q.cond.Wait()
+		}
+	}
+
+	if q.closed {
+		return
+	}
+
+	if len(q.pending) >= q.size {
+		q.dropped++
+		q.droppedTotal.Inc()
+		if q.policy == Coalesce && q.coalesce(msg) {
+			return
+		}
+		q.pending = q.pending[1:]
+		q.depth.Dec()
+	}
+
+	q.pending = append(q.pending, msg)
+	q.depth.Inc()
+	q.cond.Broadcast()
+}
+
+// coalesce replaces the last pending message for the item if it has the
+// same type. Replacing an earlier message would reorder the item's
+// messages, so anything else is left alone.
+func (q *Queue) coalesce(msg types.Message) bool {
+	for i := len(q.pending) - 1; i >= 0; i-- {
+		if q.pending[i].ItemName != msg.ItemName {
+			continue
+		}
+		if q.pending[i].Type != msg.Type {
+			return false
+		}
+		q.pending[i] = msg
+		return true
+	}
+	return false
+}
+
+func (q *Queue) Messages() <-chan types.Message {
+	return q.out
+}
+
+// Dropped returns the number of messages lost to the overflow policy.
+func (q *Queue) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Close stops accepting messages. Pending messages are still delivered
+// before the Messages channel is closed.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
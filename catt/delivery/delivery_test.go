@@ -0,0 +1,95 @@
+package delivery
+
+import (
+	"testing"
+
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func command(item string, on bool) types.Message {
+	val := types.NewBoolValue(on)
+	return types.Message{
+		Type:     types.CommandMessage,
+		ItemName: item,
+		Value:    &val,
+	}
+}
+
+func drain(q *Queue) []types.Message {
+	q.Close()
+	var msgs []types.Message
+	for msg := range q.Messages() {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestOrder(t *testing.T) {
+	q := NewQueue("test", 0, Block)
+	for i := 0; i < 100; i++ {
+		q.Push(command("a", i%2 == 0))
+	}
+
+	msgs := drain(q)
+	if len(msgs) != 100 {
+		t.Fatalf("expected 100 messages, got %d", len(msgs))
+	}
+	for i, msg := range msgs {
+		if on, _ := msg.Value.AsBool(); on != (i%2 == 0) {
+			t.Fatalf("message %d delivered out of order", i)
+		}
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	// The pump holds on to the first message until it is consumed, so a
+	// queue of size 2 has room for three messages before overflowing.
+	for _, tc := range []struct {
+		policy Policy
+		want   []string
+	}{
+		{DropOldest, []string{"a", "c", "b"}},
+		{Coalesce, []string{"a", "b", "c"}},
+	} {
+		// The metrics are global, so only their change is checked.
+		bus := "test-" + tc.policy.String()
+		depth := QueueDepth.WithLabelValues(bus, "receive")
+		dropped := DroppedMessages.WithLabelValues(bus, "receive")
+		depthBefore := testutil.ToFloat64(depth)
+		droppedBefore := testutil.ToFloat64(dropped)
+
+		q := NewQueue(bus, 2, tc.policy)
+		q.Push(command("a", true))
+		q.mu.Lock()
+		for len(q.pending) != 0 {
+			q.cond.Wait()
+		}
+		q.mu.Unlock()
+		q.Push(command("b", true))
+		q.Push(command("c", true))
+		q.Push(command("b", false))
+		if n := testutil.ToFloat64(depth) - depthBefore; n != 2 {
+			t.Fatalf("%s: expected a queue depth of 2, got %v", tc.policy, n)
+		}
+
+		msgs := drain(q)
+		if len(msgs) != len(tc.want) {
+			t.Fatalf("%s: expected %d messages, got %d", tc.policy, len(tc.want), len(msgs))
+		}
+		for i, msg := range msgs {
+			if msg.ItemName != tc.want[i] {
+				t.Fatalf("%s: expected %v, got %v", tc.policy, tc.want, msgs)
+			}
+		}
+		if last, _ := msgs[len(msgs)-1].Value.AsBool(); tc.policy == DropOldest && last {
+			t.Fatalf("%s: expected latest value for b", tc.policy)
+		}
+		if q.Dropped() != 1 {
+			t.Fatalf("%s: expected 1 dropped message, got %d", tc.policy, q.Dropped())
+		}
+		if n := testutil.ToFloat64(dropped) - droppedBefore; n != 1 {
+			t.Fatalf("%s: expected 1 dropped message in the metrics, got %v", tc.policy, n)
+		}
+	}
+}
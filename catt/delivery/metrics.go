@@ -0,0 +1,20 @@
+package delivery
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The queues of every bus report through these, labeled with the bus type
+// and "receive" or "publish".
+var (
+	DroppedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_bus_dropped_messages_total",
+		Help: "Messages lost to a full queue, by bus and queue.",
+	}, []string{"bus", "queue"})
+
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "catt_bus_queue_depth",
+		Help: "Messages waiting in a queue, by bus and queue.",
+	}, []string{"bus", "queue"})
+)
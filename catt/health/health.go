@@ -0,0 +1,177 @@
+// Package health aggregates the status of the bridge's components and
+// reports it over HTTP and to systemd.
+package health
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/logging"
+)
+
+var log = logging.Logger("health")
+
+// Config configures the health endpoints.
+type Config struct {
+	// Listen is the address to serve /livez and /readyz on. The endpoints
+	// are disabled if empty.
+	Listen string `toml:"listen"`
+}
+
+// Checker is implemented by components that can report their health. A nil
+// error means healthy.
+type Checker interface {
+	Health() error
+}
+
+// CheckFunc adapts a function to a Checker.
+type CheckFunc func() error
+
+func (f CheckFunc) Health() error {
+	return f()
+}
+
+type check struct {
+	checker  Checker
+	liveness bool
+}
+
+// Health holds a set of named checks. Liveness checks fail only if the
+// process is broken beyond repair and should be restarted; readiness
+// checks, which include the liveness checks, fail while the process can't
+// do its job, e.g. because the bus is disconnected.
+type Health struct {
+	mu     sync.Mutex
+	checks map[string]check
+}
+
+// Status is the result of one check.
+type Status struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the result of a set of checks.
+type Report struct {
+	Ok     bool              `json:"ok"`
+	Checks map[string]Status `json:"checks"`
+}
+
+func New() *Health {
+	return &Health{
+		checks: make(map[string]check),
+	}
+}
+
+// AddLiveness registers a check that affects liveness and readiness.
+func (h *Health) AddLiveness(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check{checker: c, liveness: true}
+}
+
+// AddReadiness registers a check that affects readiness only.
+func (h *Health) AddReadiness(name string, c Checker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check{checker: c}
+}
+
+func (h *Health) run(livenessOnly bool) Report {
+	h.mu.Lock()
+	checks := make(map[string]check, len(h.checks))
+	for name, c := range h.checks {
+		checks[name] = c
+	}
+	h.mu.Unlock()
+
+	report := Report{
+		Ok:     true,
+		Checks: make(map[string]Status),
+	}
+	for name, c := range checks {
+		if livenessOnly && !c.liveness {
+			continue
+		}
+
+		status := Status{Ok: true}
+		if err := c.checker.Health(); err != nil {
+			status = Status{Error: err.Error()}
+			report.Ok = false
+		}
+		report.Checks[name] = status
+	}
+
+	return report
+}
+
+// Live runs the liveness checks.
+func (h *Health) Live() Report {
+	return h.run(true)
+}
+
+// Ready runs all checks.
+func (h *Health) Ready() Report {
+	return h.run(false)
+}
+
+// Failures lists the failed checks of a report as "name: error", sorted by
+// name.
+func (r Report) Failures() []string {
+	var failures []string
+	for name, status := range r.Checks {
+		if !status.Ok {
+			failures = append(failures, name+": "+status.Error)
+		}
+	}
+	sort.Strings(failures)
+	return failures
+}
+
+func serveReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if !report.Ok {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Handler serves /livez and /readyz. They answer 200 if the checks pass
+// and 503 otherwise, with the report as JSON.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		serveReport(w, h.Live())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		serveReport(w, h.Ready())
+	})
+	return mux
+}
+
+// Listen serves the health endpoints on cfg.Listen.
+func (h *Health) Listen(cfg Config) (*http.Server, error) {
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: h.Handler()}
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("health server stopped")
+		}
+	}()
+
+	return srv, nil
+}
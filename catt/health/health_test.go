@@ -0,0 +1,69 @@
+package health
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var busErr error
+	h := New()
+	h.AddLiveness("bridge", CheckFunc(func() error { return nil }))
+	h.AddReadiness("bus", CheckFunc(func() error { return busErr }))
+
+	hs := httptest.NewServer(h.Handler())
+	defer hs.Close()
+
+	status := func(path string) int {
+		resp, err := http.Get(hs.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if s := status("/readyz"); s != http.StatusOK {
+		t.Fatalf("expected ready, got %d", s)
+	}
+
+	busErr = errors.New("not connected to broker")
+	if s := status("/readyz"); s != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready, got %d", s)
+	}
+	if s := status("/livez"); s != http.StatusOK {
+		t.Fatalf("expected a readiness failure not to affect liveness, got %d", s)
+	}
+
+	failures := h.Ready().Failures()
+	if len(failures) != 1 || failures[0] != "bus: not connected to broker" {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	if err := Notify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "READY=1" {
+		t.Fatalf("unexpected notification: %q", buf[:n])
+	}
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Notify sends a state string like "READY=1" to systemd over the socket in
+// NOTIFY_SOCKET. It does nothing if the process wasn't started by systemd
+// with notification access.
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// A leading @ denotes a socket in the abstract namespace.
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns the interval systemd expects watchdog pings at,
+// or 0 if the watchdog isn't enabled for this process.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Systemd tells systemd that the service is ready and, if the watchdog is
+// enabled, keeps pinging it at half the watchdog interval as long as the
+// liveness checks pass. Readiness failures are reported as the service
+// status. It returns right away if not running under systemd.
+func (h *Health) Systemd() {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+
+	if err := Notify("READY=1"); err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("error notifying systemd")
+		return
+	}
+
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	go func() {
+		var lastStatus string
+		for range time.NewTicker(interval / 2).C {
+			if live := h.Live(); !live.Ok {
+				log.WithFields(logrus.Fields{
+					"failures": live.Failures(),
+				}).Warn("liveness check failed, withholding watchdog ping")
+				continue
+			}
+
+			state := "WATCHDOG=1"
+			status := "ready"
+			if ready := h.Ready(); !ready.Ok {
+				status = "not ready: " + strings.Join(ready.Failures(), ", ")
+			}
+			if status != lastStatus {
+				state += "\nSTATUS=" + status
+				lastStatus = status
+			}
+
+			if err := Notify(state); err != nil {
+				log.WithFields(logrus.Fields{
+					"error": err,
+				}).Warn("error pinging systemd watchdog")
+			}
+		}
+	}()
+}
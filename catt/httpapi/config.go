@@ -0,0 +1,14 @@
+package httpapi
+
+// ServerConfig configures the HTTP API of a bridge.
+type ServerConfig struct {
+	// Listen is the address to serve on. The server is disabled if empty.
+	Listen string `toml:"listen"`
+
+	// Dashboard serves the web dashboard at /.
+	Dashboard bool `toml:"dashboard"`
+
+	// Username and Password enable basic authentication.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
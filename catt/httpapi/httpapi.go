@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/catt-ha/catt-go/catt/web"
+)
+
+var log = logging.Logger("http")
+
+const (
+	maxBodySize       = 1 << 20
+	keepAliveInterval = 30 * time.Second
+)
+
+// Server is a REST API over the items of a bridge:
+//
+//	GET  /items                 all items
+//	GET  /items/{name}          a single item
+//	POST /items/{name}/command  set an item's value
+//	GET  /events                server-sent events for every notification
+//	GET  /                      the web dashboard, if enabled
+//
+// Items are encoded as
+//
+//	{"name": "Light_Switch", "value": {"type": "bool", "value": true}, "meta": {...}}
+//
+// Commands take a value in the same form as a JSON body. Any other body is
+// interpreted like a raw MQTT payload. Commands sent by browsers from other
+// sites are refused, since those need no CORS preflight.
+type Server struct {
+	cfg      ServerConfig
+	registry types.Registry
+	mux      *http.ServeMux
+}
+
+type item struct {
+	Name  string       `json:"name"`
+	Value *types.Value `json:"value,omitempty"`
+	Meta  *types.Meta  `json:"meta,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+func NewServer(cfg ServerConfig, registry types.Registry) *Server {
+	s := &Server{
+		cfg:      cfg,
+		registry: registry,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /items", s.listItems)
+	s.mux.HandleFunc("GET /items/{name}", s.getItem)
+	s.mux.HandleFunc("POST /items/{name}/command", s.sendCommand)
+	s.mux.HandleFunc("GET /events", s.events)
+
+	if cfg.Dashboard {
+		s.mux.Handle("GET /", web.Handler())
+	}
+
+	return s
+}
+
+// Listen creates a Server and serves it on cfg.Listen.
+func Listen(cfg ServerConfig, registry types.Registry) (*http.Server, error) {
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: NewServer(cfg, registry)}
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("http server stopped")
+		}
+	}()
+
+	return srv, nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.cfg.Username == "" {
+		return true
+	}
+
+	user, pass, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(s.cfg.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(s.cfg.Password)) == 1
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="catt"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func encodeItem(i types.Item) item {
+	out := item{
+		Name: i.GetName(),
+		Meta: i.GetMeta(),
+	}
+
+	value, err := i.GetValue()
+	if err == nil {
+		// Catch values that can't be encoded now rather than failing the
+		// whole response.
+		_, err = value.MarshalJSON()
+	}
+	if err != nil {
+		out.Error = err.Error()
+	} else {
+		out.Value = &value
+	}
+
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("error writing response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+func (s *Server) listItems(w http.ResponseWriter, r *http.Request) {
+	items := s.registry.Items()
+	out := make([]item, 0, len(items))
+	for _, i := range items {
+		out = append(out, encodeItem(i))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getItem(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	i := s.registry.Item(name)
+	if i == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such item: %s", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, encodeItem(i))
+}
+
+// decodeCommand reads a command body. Bodies that aren't JSON are guessed
+// like raw bus payloads, and the payload is kept for typed items.
+func decodeCommand(r *http.Request) (types.Message, error) {
+	msg := types.Message{
+		Type:     types.CommandMessage,
+		ItemName: r.PathValue("name"),
+		Value:    new(types.Value),
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return msg, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		msg.Value.FromRaw(body)
+		msg.Raw = body
+		return msg, nil
+	}
+
+	*msg.Value, msg.Raw, err = types.UnmarshalValue(body)
+	return msg, err
+}
+
+// crossSite reports whether a browser sent a request on behalf of another
+// site. Clients other than browsers send neither header.
+func crossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || !strings.EqualFold(u.Host, r.Host)
+}
+
+func (s *Server) sendCommand(w http.ResponseWriter, r *http.Request) {
+	if crossSite(r) {
+		writeError(w, http.StatusForbidden, errors.New("cross-site commands are not allowed"))
+		return
+	}
+
+	msg, err := decodeCommand(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = s.registry.SendCommand(msg)
+	switch {
+	case errors.Is(err, types.ErrNoSuchItem):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, types.ErrInvalidValue):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, types.ErrReadOnly):
+		writeError(w, http.StatusForbidden, err)
+	case err != nil:
+		log.WithFields(logrus.Fields{
+			"item":  msg.ItemName,
+			"error": err,
+		}).Warn("error setting item value")
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// events streams notifications as server-sent events named added, changed
+// and removed, each carrying the encoded item.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	notifications, stop := s.registry.Watch()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case notification, ok := <-notifications:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(encodeItem(notification.Item))
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", notification.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package httpapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+type testItem struct {
+	mu    sync.Mutex
+	name  string
+	value types.Value
+}
+
+func (i *testItem) GetName() string { return i.name }
+
+func (i *testItem) GetMeta() *types.Meta {
+	return &types.Meta{Backend: "test", ValueType: "bool"}
+}
+
+func (i *testItem) GetValue() (types.Value, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.value, nil
+}
+
+func (i *testItem) SetValue(v types.Value) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.value = v
+	return nil
+}
+
+type testRegistry struct {
+	item  *testItem
+	watch chan types.Notification
+
+	mu       sync.Mutex
+	commands []types.Message
+	err      error
+}
+
+func (r *testRegistry) Items() []types.Item { return []types.Item{r.item} }
+
+func (r *testRegistry) Item(name string) types.Item {
+	if name == r.item.name {
+		return r.item
+	}
+	return nil
+}
+
+func (r *testRegistry) Watch() (<-chan types.Notification, func()) {
+	return r.watch, func() {}
+}
+
+// SendCommand records commands and fails them with err. Coercing them is
+// the bridge's job and is tested there.
+func (r *testRegistry) SendCommand(msg types.Message) error {
+	if msg.ItemName != r.item.name {
+		return fmt.Errorf("%w: %s", types.ErrNoSuchItem, msg.ItemName)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, msg)
+	return r.err
+}
+
+func (r *testRegistry) lastCommand(t *testing.T) types.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.commands) == 0 {
+		t.Fatal("expected a command")
+	}
+	return r.commands[len(r.commands)-1]
+}
+
+func TestHttpApi(t *testing.T) {
+	it := &testItem{name: "Light_Switch", value: types.NewBoolValue(false)}
+	registry := &testRegistry{item: it, watch: make(chan types.Notification, 1)}
+
+	hs := httptest.NewServer(NewServer(ServerConfig{}, registry))
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL + "/items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []item
+	err = json.NewDecoder(resp.Body).Decode(&items)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Name != "Light_Switch" || items[0].Value == nil || items[0].Value.Type != types.BoolValue || items[0].Meta.Backend != "test" {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+
+	resp, err = http.Get(hs.URL + "/items/Missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(hs.URL+"/items/Light_Switch/command", "application/json",
+		strings.NewReader(`{"type": "bool", "value": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if msg := registry.lastCommand(t); msg.ItemName != "Light_Switch" || msg.Value.Type != types.BoolValue || msg.Raw != nil {
+		t.Fatalf("expected a typed bool command, got %+v", msg)
+	} else if b, _ := msg.Value.AsBool(); !b {
+		t.Fatal("expected command to switch the item on")
+	}
+
+	// A bare JSON string keeps its text, to be parsed as the item's type.
+	resp, err = http.Post(hs.URL+"/items/Light_Switch/command", "application/json",
+		strings.NewReader(`"2024-05-01"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	if msg := registry.lastCommand(t); string(msg.Raw) != "2024-05-01" {
+		t.Fatalf("expected the raw string, got %+v", msg)
+	}
+
+	resp, err = http.Post(hs.URL+"/items/Light_Switch/command", "application/json",
+		strings.NewReader(`{"type": "bool", "value": "maybe"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+
+	registry.mu.Lock()
+	registry.err = fmt.Errorf("%w: Light_Switch", types.ErrReadOnly)
+	registry.mu.Unlock()
+	resp, err = http.Post(hs.URL+"/items/Light_Switch/command", "text/plain", strings.NewReader("ON"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+
+	// A page on another site can post a text body without a preflight.
+	registry.mu.Lock()
+	registry.err = nil
+	sent := len(registry.commands)
+	registry.mu.Unlock()
+	for header, value := range map[string]string{
+		"Origin":         "http://evil.example",
+		"Sec-Fetch-Site": "cross-site",
+	} {
+		req, _ := http.NewRequest(http.MethodPost, hs.URL+"/items/Light_Switch/command", strings.NewReader("ON"))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set(header, value)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("%s: expected 403, got %d", header, resp.StatusCode)
+		}
+	}
+	registry.mu.Lock()
+	if len(registry.commands) != sent {
+		t.Fatal("cross-site command reached the registry")
+	}
+	registry.mu.Unlock()
+
+	resp, err = http.Get(hs.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	registry.watch <- types.Notification{Type: types.ChangedNotification, Item: it}
+
+	r := bufio.NewReader(resp.Body)
+	event, _ := r.ReadString('\n')
+	data, _ := r.ReadString('\n')
+	if event != "event: changed\n" || !strings.Contains(data, `"name":"Light_Switch"`) {
+		t.Fatalf("unexpected event: %q %q", event, data)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	registry := &testRegistry{item: &testItem{name: "Light_Switch"}}
+
+	hs := httptest.NewServer(NewServer(ServerConfig{Dashboard: true}, registry))
+	defer hs.Close()
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		resp, err := http.Get(hs.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package hue
+
+type Config struct {
+	// Disabled turns the Hue binding off.
+	Disabled bool `toml:"disabled"`
+	// Address is the IP or host name of the Hue bridge. The bridge is
+	// discovered on the network if empty.
+	Address string `toml:"address"`
+	// Username is the API user created by pairing. Pairing is started if
+	// empty, which requires pressing the link button on the bridge.
+	Username string `toml:"username"`
+	// PollInterval is the number of seconds between light refreshes.
+	// Defaults to DefaultPollInterval.
+	PollInterval int `toml:"poll_interval"`
+}
@@ -1,28 +1,53 @@
 package hue
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/logging"
 	"github.com/catt-ha/catt-go/catt/types"
 	"github.com/gbbr/hue"
 )
 
-var log = logrus.New()
+var log = logging.Logger("hue")
+
+const DefaultPollInterval = 5
 
 type Hue struct {
 	mu            sync.Mutex
+	pollInterval  time.Duration
 	internal      *hue.Bridge
 	items         map[string]*HueItem
 	notifications chan types.Notification
+	done          chan struct{}
+	closeOnce     sync.Once
+
+	statusMu    sync.Mutex
+	lastPoll    time.Time
+	lastPollErr error
 }
 
-func NewHue() (*Hue, error) {
-	b, err := hue.Discover()
-	if err != nil {
-		return nil, err
+func NewHue(cfg Config) (*Hue, error) {
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+
+	var b *hue.Bridge
+	if cfg.Address != "" {
+		b = &hue.Bridge{IP: cfg.Address}
+	} else {
+		var err error
+		if b, err = hue.Discover(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Username != "" {
+		b.Username = cfg.Username
 	}
+
 	if !b.IsPaired() {
 		// link button must be pressed before calling
 		log.Info("Please press the link button on your Hue!")
@@ -33,9 +58,11 @@ func NewHue() (*Hue, error) {
 		log.Info("Pairing successful!")
 	}
 	binding := &Hue{
+		pollInterval:  time.Duration(cfg.PollInterval) * time.Second,
 		internal:      b,
 		items:         make(map[string]*HueItem),
 		notifications: make(chan types.Notification),
+		done:          make(chan struct{}),
 	}
 
 	startWatcher(binding)
@@ -45,14 +72,30 @@ func NewHue() (*Hue, error) {
 
 func startWatcher(binding *Hue) {
 	go func() {
-		for range time.NewTicker(5 * time.Second).C {
+		ticker := time.NewTicker(binding.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-binding.done:
+				return
+			}
+
 			binding.mu.Lock()
+			start := time.Now()
 			lights, err := binding.internal.Lights().List()
+			pollDuration.Observe(time.Since(start).Seconds())
+			binding.setPollResult(err)
 			if err != nil {
+				pollErrors.Inc()
 				log.WithFields(logrus.Fields{
 					"error": err,
 				}).Warn("error refreshing lights")
+				// Don't mistake a failed poll for every light disappearing.
+				binding.mu.Unlock()
+				continue
 			}
+			lightCount.Set(float64(len(lights)))
 			lightsMap := buildMap(lights)
 			for k, v := range lightsMap {
 				if i, ok := binding.items[k]; !ok {
@@ -60,18 +103,22 @@ func startWatcher(binding *Hue) {
 						itemType: v.itemType,
 						light:    v.light,
 						updates:  binding.notifications,
+						done:     binding.done,
 					}
 					binding.items[k] = newItem
-					binding.notifications <- types.Notification{
+					binding.notify(types.Notification{
 						Type: types.AddedNotification,
 						Item: newItem,
-					}
+					})
 				} else {
 					if lightChanged(i, v) {
-						binding.notifications <- types.Notification{
+						i.mu.Lock()
+						i.light = v.light
+						i.mu.Unlock()
+						binding.notify(types.Notification{
 							Type: types.ChangedNotification,
 							Item: i,
-						}
+						})
 					}
 				}
 			}
@@ -79,19 +126,62 @@ func startWatcher(binding *Hue) {
 			for k, v := range binding.items {
 				if _, ok := lightsMap[k]; !ok {
 					toDelete = append(toDelete, k)
-					binding.notifications <- types.Notification{
+					binding.notify(types.Notification{
 						Type: types.RemovedNotification,
 						Item: v,
-					}
+					})
 				}
 			}
 			for _, v := range toDelete {
 				delete(binding.items, v)
 			}
+			binding.mu.Unlock()
 		}
 	}()
 }
 
+// notify hands a notification to the bridge, unless the binding is closed.
+func (h *Hue) notify(n types.Notification) {
+	select {
+	case h.notifications <- n:
+	case <-h.done:
+	}
+}
+
+// Close stops polling the Hue bridge.
+func (h *Hue) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+func (h *Hue) setPollResult(err error) {
+	h.statusMu.Lock()
+	defer h.statusMu.Unlock()
+	h.lastPollErr = err
+	if err == nil {
+		h.lastPoll = time.Now()
+	}
+}
+
+// Health reports an error unless the lights were listed successfully
+// within the last few poll intervals.
+func (h *Hue) Health() error {
+	h.statusMu.Lock()
+	defer h.statusMu.Unlock()
+
+	if time.Since(h.lastPoll) <= 3*h.pollInterval {
+		return nil
+	}
+	if h.lastPollErr != nil {
+		return fmt.Errorf("polling lights: %v", h.lastPollErr)
+	}
+	if h.lastPoll.IsZero() {
+		return errors.New("lights not polled yet")
+	}
+	return fmt.Errorf("last successful poll at %s", h.lastPoll.Format(time.RFC3339))
+}
+
 func buildMap(lights []*hue.Light) map[string]*HueItem {
 	m := make(map[string]*HueItem)
 	for _, v := range lights {
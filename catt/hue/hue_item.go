@@ -31,6 +31,7 @@ type HueItem struct {
 	light    *hue.Light
 	mu       sync.Mutex
 	updates  chan types.Notification
+	done     <-chan struct{}
 }
 
 var _ types.Item = &HueItem{}
@@ -48,11 +49,25 @@ func (hi *HueItem) GetName() string {
 }
 
 func (hi *HueItem) GetMeta() *types.Meta {
-	return &types.Meta{
+	hi.mu.Lock()
+	label := hi.light.Name
+	hi.mu.Unlock()
+
+	meta := &types.Meta{
 		Backend:   "hue",
 		ValueType: hi.itemType.String(),
 		Ext:       nil,
+		Label:     label,
+		Category:  "light",
+		Tags:      []string{"lighting"},
+	}
+	if hi.itemType == colorType {
+		meta.Label += " color"
+		meta.Tags = append(meta.Tags, "color")
+	} else {
+		meta.Tags = append(meta.Tags, "switch")
 	}
+	return meta
 }
 
 func (hi *HueItem) GetValue() (types.Value, error) {
@@ -97,9 +112,12 @@ func (hi *HueItem) SetValue(val types.Value) error {
 			return err
 		}
 	}
-	hi.updates <- types.Notification{
+	select {
+	case hi.updates <- types.Notification{
 		Type: types.ChangedNotification,
 		Item: hi,
+	}:
+	case <-hi.done:
 	}
 	return nil
 }
@@ -108,7 +126,7 @@ func toCattColor(state *hue.LightState) types.Value {
 	h := float64(float64(state.Hue) * (360.0 / 65535.0))
 	s := float64(float64(state.Saturation) * (1.0 / 255.0))
 	v := float64(float64(state.Brightness) * (1.0 / 255.0))
-	return types.NewColorValue(types.Color{h, s, v})
+	return types.NewColorValue(types.Color{H: h, S: s, V: v})
 }
 
 func fromCattColor(val types.Color) (h uint16, s uint8, v uint8) {
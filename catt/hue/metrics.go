@@ -0,0 +1,24 @@
+package hue
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	pollDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "catt_hue_poll_duration_seconds",
+		Help:    "Time taken to list the lights on the Hue bridge.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	pollErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "catt_hue_poll_errors_total",
+		Help: "Failed attempts to list the lights on the Hue bridge.",
+	})
+
+	lightCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "catt_hue_lights",
+		Help: "Number of lights found by the last successful poll.",
+	})
+)
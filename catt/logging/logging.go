@@ -0,0 +1,214 @@
+// Package logging hands out the per-component loggers used throughout catt
+// and configures them from one place.
+//
+//	[log]
+//	level = "info"
+//	format = "json"
+//
+//	[log.components]
+//	mqtt = "debug"
+//	hue = "warn"
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+// Field names shared by all components.
+const (
+	FieldItem        = "item"
+	FieldBackend     = "backend"
+	FieldMessageType = "message_type"
+	FieldComponent   = "component"
+)
+
+// Config configures all loggers.
+type Config struct {
+	// Level is the default level: panic, fatal, error, warn, info or
+	// debug. Defaults to info.
+	Level string `toml:"level"`
+	// Format is text or json. Defaults to text.
+	Format string `toml:"format"`
+	// Components overrides the level per component.
+	Components map[string]string `toml:"components"`
+}
+
+var (
+	mu        sync.Mutex
+	loggers   = make(map[string]*logrus.Logger)
+	levels    map[string]logrus.Level
+	level     = logrus.InfoLevel
+	formatter logrus.Formatter
+	output    io.Writer = os.Stderr
+)
+
+// Logger returns the logger for a component, creating it if needed. Its
+// entries carry the component in FieldComponent. Loggers created before
+// Configure is called are reconfigured by it.
+func Logger(component string) *logrus.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if l, ok := loggers[component]; ok {
+		return l
+	}
+
+	l := logrus.New()
+	l.Hooks.Add(componentHook(component))
+	apply(component, l)
+	loggers[component] = l
+	return l
+}
+
+// apply configures a logger from the current settings. Must be called with
+// mu held.
+func apply(component string, l *logrus.Logger) {
+	l.SetOutput(output)
+	if formatter != nil {
+		l.Formatter = formatter
+	}
+	if lvl, ok := levels[component]; ok {
+		l.SetLevel(lvl)
+	} else {
+		l.SetLevel(level)
+	}
+}
+
+// componentHook adds the component to every entry of its logger.
+type componentHook string
+
+func (h componentHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h componentHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[FieldComponent]; !ok {
+		entry.Data[FieldComponent] = string(h)
+	}
+	return nil
+}
+
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", "text":
+		return &logrus.TextFormatter{}, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", format)
+	}
+}
+
+func parseLevel(s string) (logrus.Level, error) {
+	if s == "" {
+		return logrus.InfoLevel, nil
+	}
+	return logrus.ParseLevel(s)
+}
+
+type settings struct {
+	formatter logrus.Formatter
+	level     logrus.Level
+	levels    map[string]logrus.Level
+}
+
+func parse(cfg Config) (settings, error) {
+	f, err := newFormatter(cfg.Format)
+	if err != nil {
+		return settings{}, err
+	}
+
+	lvl, err := parseLevel(cfg.Level)
+	if err != nil {
+		return settings{}, err
+	}
+
+	lvls := make(map[string]logrus.Level)
+	for component, s := range cfg.Components {
+		l, err := parseLevel(s)
+		if err != nil {
+			return settings{}, fmt.Errorf("component %s: %v", component, err)
+		}
+		lvls[component] = l
+	}
+
+	return settings{formatter: f, level: lvl, levels: lvls}, nil
+}
+
+// Validate checks cfg without applying it.
+func Validate(cfg Config) error {
+	_, err := parse(cfg)
+	return err
+}
+
+// Configure applies cfg to every logger, existing and future. Nothing is
+// changed if cfg is invalid.
+func Configure(cfg Config) error {
+	s, err := parse(cfg)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	formatter = s.formatter
+	level = s.level
+	levels = s.levels
+	for component, l := range loggers {
+		apply(component, l)
+	}
+
+	return nil
+}
+
+// SetOutput redirects every logger, e.g. to capture logs in tests.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	output = w
+	for component, l := range loggers {
+		apply(component, l)
+	}
+}
+
+// Components lists the components that have a logger, sorted by name.
+func Components() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	components := make([]string, 0, len(loggers))
+	for component := range loggers {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	return components
+}
+
+// ItemFields returns the standard fields describing an item.
+func ItemFields(item types.Item) logrus.Fields {
+	fields := logrus.Fields{
+		FieldItem: item.GetName(),
+	}
+	if meta := item.GetMeta(); meta != nil && meta.Backend != "" {
+		fields[FieldBackend] = meta.Backend
+	}
+	return fields
+}
+
+// MessageFields returns the standard fields describing a bus message.
+func MessageFields(message types.Message) logrus.Fields {
+	return logrus.Fields{
+		FieldItem:        message.ItemName,
+		FieldMessageType: message.Type.String(),
+	}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+// restore undoes changes to the global settings at the end of a test.
+func restore(t *testing.T) {
+	mu.Lock()
+	prevOutput, prevFormatter, prevLevel, prevLevels := output, formatter, level, levels
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		output, formatter, level, levels = prevOutput, prevFormatter, prevLevel, prevLevels
+		for component, l := range loggers {
+			if formatter == nil {
+				l.Formatter = new(logrus.TextFormatter)
+			}
+			apply(component, l)
+		}
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	restore(t)
+	buf := &bytes.Buffer{}
+	SetOutput(buf)
+
+	mqtt := Logger("test-mqtt")
+	hue := Logger("test-hue")
+
+	if err := Configure(Config{Format: "xml"}); err == nil {
+		t.Fatal("expected invalid format to fail")
+	}
+	if err := Configure(Config{Components: map[string]string{"test-hue": "loud"}}); err == nil {
+		t.Fatal("expected invalid component level to fail")
+	}
+
+	if err := Configure(Config{
+		Level:      "warn",
+		Format:     "json",
+		Components: map[string]string{"test-hue": "debug"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if mqtt.Level != logrus.WarnLevel || hue.Level != logrus.DebugLevel {
+		t.Fatalf("unexpected levels: mqtt %s, hue %s", mqtt.Level, hue.Level)
+	}
+	if Logger("test-late").Level != logrus.WarnLevel {
+		t.Fatal("expected loggers created later to be configured too")
+	}
+
+	mqtt.Info("dropped")
+	if buf.Len() != 0 {
+		t.Fatalf("expected info to be filtered, got %q", buf.String())
+	}
+
+	mqtt.WithFields(MessageFields(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Light_Switch",
+	})).Warn("hello")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry[FieldItem] != "Light_Switch" || entry[FieldMessageType] != "command" || entry["msg"] != "hello" || entry[FieldComponent] != "test-mqtt" {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+}
@@ -0,0 +1,39 @@
+package catt
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_bridge_messages_received_total",
+		Help: "Messages received from the bus, by type.",
+	}, []string{"type"})
+
+	messagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_bridge_messages_published_total",
+		Help: "Messages published to the bus, by type.",
+	}, []string{"type"})
+
+	publishErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_bridge_publish_errors_total",
+		Help: "Messages that failed to publish, by type.",
+	}, []string{"type"})
+
+	commandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_bridge_command_errors_total",
+		Help: "Commands that could not be applied, by reason.",
+	}, []string{"reason"})
+
+	notificationsHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_bridge_notifications_total",
+		Help: "Notifications received from the binding, by type.",
+	}, []string{"type"})
+
+	setValueDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "catt_bridge_set_value_duration_seconds",
+		Help:    "Time taken by the binding to apply a command.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend"})
+)
@@ -0,0 +1,121 @@
+// Package metrics exposes the Prometheus metrics registered by the bridge,
+// the buses and the bindings, and optionally the values of all items.
+package metrics
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var log = logging.Logger("metrics")
+
+// Config configures the metrics endpoint.
+type Config struct {
+	// Listen is the address to serve /metrics on. The endpoint is disabled
+	// if empty.
+	Listen string `toml:"listen"`
+
+	// Items exports the value of every number and bool item as the
+	// catt_item_value gauge.
+	Items bool `toml:"items"`
+}
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Listen serves /metrics on cfg.Listen. The item collector is registered
+// with the server rather than globally, so the server can be restarted.
+func Listen(cfg Config, registry types.Registry) (*http.Server, error) {
+	handler := Handler()
+	if cfg.Items {
+		items := prometheus.NewRegistry()
+		if err := items.Register(NewItemCollector(registry)); err != nil {
+			return nil, err
+		}
+		gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, items}
+		handler = promhttp.InstrumentMetricHandler(
+			prometheus.DefaultRegisterer,
+			promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}),
+		)
+	}
+
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", handler)
+	srv := &http.Server{Handler: mux}
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("metrics server stopped")
+		}
+	}()
+
+	return srv, nil
+}
+
+var itemValueDesc = prometheus.NewDesc(
+	"catt_item_value",
+	"Current value of number, quantity, percent and bool items. Bools are 1 for on and 0 for off.",
+	[]string{"item", "backend"},
+	nil,
+)
+
+// ItemCollector reports the current values of the items in a registry.
+type ItemCollector struct {
+	registry types.Registry
+}
+
+func NewItemCollector(registry types.Registry) *ItemCollector {
+	return &ItemCollector{registry: registry}
+}
+
+func (c *ItemCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- itemValueDesc
+}
+
+func (c *ItemCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, item := range c.registry.Items() {
+		value, err := item.GetValue()
+		if err != nil {
+			continue
+		}
+
+		var v float64
+		switch value.Type {
+		case types.NumberValue, types.QuantityValue, types.PercentValue:
+			v, err = value.AsNumber()
+		case types.BoolValue:
+			var b bool
+			b, err = value.AsBool()
+			if b {
+				v = 1
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		backend := ""
+		if meta := item.GetMeta(); meta != nil {
+			backend = meta.Backend
+		}
+
+		ch <- prometheus.MustNewConstMetric(itemValueDesc, prometheus.GaugeValue, v, item.GetName(), backend)
+	}
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type testItem struct {
+	name  string
+	value types.Value
+}
+
+func (i *testItem) GetName() string                { return i.name }
+func (i *testItem) GetMeta() *types.Meta           { return &types.Meta{Backend: "test"} }
+func (i *testItem) GetValue() (types.Value, error) { return i.value, nil }
+func (i *testItem) SetValue(v types.Value) error   { return nil }
+
+type testRegistry []types.Item
+
+func (r testRegistry) Items() []types.Item    { return r }
+func (r testRegistry) Item(string) types.Item { return nil }
+func (r testRegistry) Watch() (<-chan types.Notification, func()) {
+	return nil, func() {}
+}
+func (r testRegistry) SendCommand(types.Message) error { return nil }
+
+func TestItemCollector(t *testing.T) {
+	c := NewItemCollector(testRegistry{
+		&testItem{name: "Light_Switch", value: types.NewBoolValue(true)},
+		&testItem{name: "Temperature", value: types.NewNumberValue(21.5)},
+		&testItem{name: "Dimmer", value: types.NewPercentValue(40)},
+		&testItem{name: "Label", value: types.NewStringValue("hello")},
+	})
+
+	expected := `
+# HELP catt_item_value Current value of number, quantity, percent and bool items. Bools are 1 for on and 0 for off.
+# TYPE catt_item_value gauge
+catt_item_value{backend="test",item="Dimmer"} 40
+catt_item_value{backend="test",item="Light_Switch"} 1
+catt_item_value{backend="test",item="Temperature"} 21.5
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
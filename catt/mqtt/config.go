@@ -1,8 +1,21 @@
 package mqtt
 
+import "github.com/catt-ha/catt-go/catt/delivery"
+
 type Config struct {
 	Broker   string `toml:"broker"`
 	ItemBase string `toml:"item_base"`
 	ClientId string `toml:"client_id"`
 	Tls      bool   `toml:"tls"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	// QueueSize bounds the number of messages held back while the broker
+	// is unreachable. Defaults to DefaultQueueSize.
+	QueueSize int `toml:"queue_size"`
+	// QueueFile, if set, persists held back messages so they survive a
+	// restart during an outage.
+	QueueFile string `toml:"queue_file"`
+
+	delivery.Config
 }
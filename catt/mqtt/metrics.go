@@ -0,0 +1,33 @@
+package mqtt
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_mqtt_messages_received_total",
+		Help: "Messages received from the broker, by type.",
+	}, []string{"type"})
+
+	messagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "catt_mqtt_messages_published_total",
+		Help: "Messages handed to the broker, by type.",
+	}, []string{"type"})
+
+	publishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "catt_mqtt_publish_failures_total",
+		Help: "Direct publishes that failed and were queued instead.",
+	})
+
+	reconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "catt_mqtt_reconnects_total",
+		Help: "Reconnections to the broker after a lost connection.",
+	})
+
+	connected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "catt_mqtt_connected",
+		Help: "Whether the broker connection is up.",
+	})
+)
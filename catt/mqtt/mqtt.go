@@ -5,28 +5,81 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/logging"
 	"github.com/catt-ha/catt-go/catt/types"
 	emqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
 )
 
-var log = logrus.New()
+var log = logging.Logger("mqtt")
+
+const (
+	publishTimeout = 5 * time.Second
+	connectTimeout = 5 * time.Second
+	retryInterval  = 3 * time.Second
+)
 
 type mqtt struct {
 	cfg    Config
 	client emqtt.Client
 	cb     emqtt.MessageHandler
+	queue  *publishQueue
+	flush  chan struct{}
+	done   chan struct{}
+
+	connectedBefore atomic.Bool
+
+	// subs are the subscribed topics, which are made again after
+	// connecting late or reconnecting since the broker doesn't keep them.
+	mu   sync.Mutex
+	subs map[string]struct{}
 }
 
 func newMqtt(cfg Config) (*mqtt, error) {
-	opts := emqtt.NewClientOptions().SetKeepAlive(5 * time.Second).SetAutoReconnect(true).SetMaxReconnectInterval(3 * time.Second)
+	queue, err := newPublishQueue(cfg.QueueSize, cfg.QueueFile)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mqtt{
+		cfg:   cfg,
+		queue: queue,
+		flush: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+		subs:  make(map[string]struct{}),
+	}
+
+	opts := emqtt.NewClientOptions().SetKeepAlive(5 * time.Second).SetAutoReconnect(true).SetMaxReconnectInterval(retryInterval)
+	opts.SetOnConnectHandler(func(emqtt.Client) {
+		if m.connectedBefore.Swap(true) {
+			reconnects.Inc()
+			go m.resubscribe()
+		}
+		connected.Set(1)
+		m.kickFlush()
+	})
+	opts.SetConnectionLostHandler(func(_ emqtt.Client, err error) {
+		connected.Set(0)
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("lost connection to broker")
+	})
 
 	if cfg.ClientId != "" {
 		opts.SetClientID(cfg.ClientId)
 	}
 
+	if cfg.Username != "" {
+		opts.SetUsername(cfg.Username)
+		opts.SetPassword(cfg.Password)
+	}
+
 	proto := "tcp"
 	if cfg.Tls {
 		proto = "ssl"
@@ -39,32 +92,90 @@ func newMqtt(cfg Config) (*mqtt, error) {
 
 	opts.AddBroker(fmt.Sprintf("%s://%s", proto, broker))
 
-	client := emqtt.NewClient(opts)
-	tok := client.Connect()
-	tok.Wait()
-	err := tok.Error()
+	m.client = emqtt.NewClient(opts)
+	go m.flusher()
 
-	if err != nil {
-		return nil, err
+	// A broker that refuses the connection is a configuration error, but
+	// one that is unreachable at startup is retried like a lost
+	// connection, with messages queued in the meantime.
+	if err := m.connect(); err != nil {
+		if !unreachable(err) {
+			close(m.done)
+			return nil, err
+		}
+		log.WithFields(logrus.Fields{
+			"broker": broker,
+			"error":  err,
+		}).Warn("broker unreachable, queueing messages until connected")
+		go m.retryConnect()
+	}
+
+	return m, nil
+}
+
+func (m *mqtt) connect() error {
+	tok := m.client.Connect()
+	if !tok.WaitTimeout(connectTimeout) {
+		return errors.New("connect timed out")
+	}
+	return tok.Error()
+}
+
+// unreachable reports whether a connect error is a network error rather
+// than the broker refusing the connection.
+func unreachable(err error) bool {
+	for code, refused := range packets.ConnErrors {
+		if code != packets.Accepted && code != packets.ErrNetworkError && err == refused {
+			return false
+		}
 	}
+	return true
+}
+
+// retryConnect connects to the broker once it is reachable. From then on
+// the client reconnects by itself.
+func (m *mqtt) retryConnect() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-time.After(retryInterval):
+		}
 
-	return &mqtt{
-		cfg:    cfg,
-		client: client,
-	}, nil
+		if err := m.connect(); err == nil {
+			select {
+			case <-m.done:
+				m.client.Disconnect(0)
+			default:
+				m.resubscribe()
+			}
+			return
+		}
+	}
 }
 
+func (m *mqtt) fullPath(subPath string) string {
+	if m.cfg.ItemBase == "" {
+		return path.Join("catt/items", subPath)
+	}
+	return path.Join(m.cfg.ItemBase, subPath)
+}
+
+// Subscribe subscribes to a topic. While the broker is unreachable, the
+// subscription is made once connected.
 func (m *mqtt) Subscribe(subPath string) error {
 	if m.cb == nil {
 		return errors.New("message handler not set")
 	}
 
-	var fullPath string
+	fullPath := m.fullPath(subPath)
 
-	if m.cfg.ItemBase == "" {
-		fullPath = path.Join("catt/items", subPath)
-	} else {
-		fullPath = path.Join(m.cfg.ItemBase, subPath)
+	m.mu.Lock()
+	m.subs[fullPath] = struct{}{}
+	m.mu.Unlock()
+
+	if !m.client.IsConnectionOpen() {
+		return nil
 	}
 
 	tok := m.client.Subscribe(fullPath, 0, m.cb)
@@ -74,31 +185,111 @@ func (m *mqtt) Subscribe(subPath string) error {
 	return tok.Error()
 }
 
+func (m *mqtt) resubscribe() {
+	m.mu.Lock()
+	topics := make([]string, 0, len(m.subs))
+	for topic := range m.subs {
+		topics = append(topics, topic)
+	}
+	m.mu.Unlock()
+
+	for _, topic := range topics {
+		tok := m.client.Subscribe(topic, 0, m.cb)
+		tok.Wait()
+		if err := tok.Error(); err != nil {
+			log.WithFields(logrus.Fields{
+				"topic": topic,
+				"error": err,
+			}).Warn("error restoring subscription")
+		}
+	}
+}
+
+// Publish sends state to the broker, or holds it back in the publish queue
+// while the broker is unreachable. Once anything is queued, later messages
+// are queued as well so that they cannot overtake older ones.
 func (m *mqtt) Publish(pubPath string, state []byte) error {
-	var fullPath string
-	if m.cfg.ItemBase == "" {
-		fullPath = path.Join("catt/items", pubPath)
-	} else {
-		fullPath = path.Join(m.cfg.ItemBase, pubPath)
+	fullPath := m.fullPath(pubPath)
+
+	if !m.client.IsConnectionOpen() || m.queue.Stats().Pending > 0 {
+		m.queue.Push(fullPath, state)
+		m.kickFlush()
+		return nil
 	}
 
-	tok := m.client.Publish(fullPath, 0, false, state)
-	tok.Wait()
+	if err := m.publish(fullPath, state); err != nil {
+		log.WithFields(logrus.Fields{
+			"topic": fullPath,
+			"error": err,
+		}).Warn("publish failed, queueing message")
+		publishFailures.Inc()
+		m.queue.Push(fullPath, state)
+	}
+
+	return nil
+}
 
+// publish sends a message straight to the broker. State and meta are
+// retained so that new subscribers, like the catt CLI, see them right away;
+// commands and errors are not, so they aren't replayed.
+func (m *mqtt) publish(topic string, payload []byte) error {
+	last := path.Base(topic)
+	retain := last == "state" || last == "meta"
+	tok := m.client.Publish(topic, 0, retain, payload)
+	if !tok.WaitTimeout(publishTimeout) {
+		return errors.New("publish timed out")
+	}
 	return tok.Error()
 }
 
+func (m *mqtt) kickFlush() {
+	select {
+	case m.flush <- struct{}{}:
+	default:
+	}
+}
+
+// flusher publishes the queue whenever kicked, retrying failed flushes for
+// as long as the connection is up.
+func (m *mqtt) flusher() {
+	for {
+		select {
+		case <-m.flush:
+		case <-m.done:
+			return
+		}
+
+		for m.client.IsConnectionOpen() {
+			err := m.queue.Flush(m.publish)
+			if err == nil {
+				break
+			}
+
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("error flushing publish queue")
+			select {
+			case <-m.done:
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}
+}
+
 func (m *mqtt) Unsubscribe(subPath string) error {
 	if m.cb == nil {
 		return errors.New("message handler not set")
 	}
 
-	var fullPath string
+	fullPath := m.fullPath(subPath)
 
-	if m.cfg.ItemBase == "" {
-		fullPath = path.Join("catt/items", subPath)
-	} else {
-		fullPath = path.Join(m.cfg.ItemBase, subPath)
+	m.mu.Lock()
+	delete(m.subs, fullPath)
+	m.mu.Unlock()
+
+	if !m.client.IsConnectionOpen() {
+		return nil
 	}
 
 	tok := m.client.Unsubscribe(fullPath)
@@ -109,17 +300,26 @@ func (m *mqtt) Unsubscribe(subPath string) error {
 }
 
 type Mqtt struct {
-	client  *mqtt
-	msgChan chan types.Message
+	client   *mqtt
+	received *delivery.Queue
+
+	closeOnce sync.Once
 }
 
 func NewMqtt(cfg Config) (*Mqtt, error) {
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
 	m, err := newMqtt(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	msgChan := make(chan types.Message, 16)
+	// paho calls the handler for one message at a time, in the order they
+	// arrive, so pushing straight onto the queue keeps them in order.
+	received := delivery.NewQueue("mqtt", cfg.Buffer, policy)
 	cb := func(cl emqtt.Client, msg emqtt.Message) {
 		splitPath := strings.Split(msg.Topic(), "/")
 		l := len(splitPath)
@@ -138,15 +338,26 @@ func NewMqtt(cfg Config) (*Mqtt, error) {
 		val := new(types.Value)
 		meta := new(types.Meta)
 		msgTypeStr := splitPath[l-1]
+
+		// An empty retained state or meta was cleared by Forget.
+		if len(msg.Payload()) == 0 && (msgTypeStr == "state" || msgTypeStr == "meta") {
+			return
+		}
+
 		switch msgTypeStr {
 		case "state":
 			val.FromRaw(msg.Payload())
 			outMsg.Type = types.UpdateMessage
 			outMsg.Value = val
+			outMsg.Raw = msg.Payload()
 		case "command":
 			val.FromRaw(msg.Payload())
 			outMsg.Type = types.CommandMessage
 			outMsg.Value = val
+			outMsg.Raw = msg.Payload()
+		case "error":
+			outMsg.Type = types.ErrorMessage
+			outMsg.Error = string(msg.Payload())
 		case "meta":
 			err := meta.FromString(string(msg.Payload()))
 			if err != nil {
@@ -165,13 +376,12 @@ func NewMqtt(cfg Config) (*Mqtt, error) {
 			return
 		}
 
-		go func() {
-			msgChan <- outMsg
-		}()
+		messagesReceived.WithLabelValues(msgTypeStr).Inc()
+		received.Push(outMsg)
 	}
 	m.cb = cb
 
-	return &Mqtt{client: m, msgChan: msgChan}, nil
+	return &Mqtt{client: m, received: received}, nil
 }
 
 func (m *Mqtt) Subscribe(itemName string, subType types.SubType) error {
@@ -228,6 +438,9 @@ func (m *Mqtt) Publish(message types.Message) error {
 	case types.MetaMessage:
 		last = "meta"
 		val, err = message.Meta.AsString()
+	case types.ErrorMessage:
+		last = "error"
+		val = message.Error
 	default:
 		return fmt.Errorf("invalid message type: %d", message.Type)
 	}
@@ -238,9 +451,42 @@ func (m *Mqtt) Publish(message types.Message) error {
 
 	pubPath := path.Join(message.ItemName, last)
 
-	return m.client.Publish(pubPath, []byte(val))
+	if err := m.client.Publish(pubPath, []byte(val)); err != nil {
+		return err
+	}
+	messagesPublished.WithLabelValues(last).Inc()
+	return nil
+}
+
+// Forget clears the retained state and meta of a removed item.
+func (m *Mqtt) Forget(itemName string) error {
+	for _, last := range []string{"state", "meta"} {
+		if err := m.client.Publish(path.Join(itemName, last), nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (m *Mqtt) Messages() <-chan types.Message {
-	return m.msgChan
+	return m.received.Messages()
+}
+
+// Health reports an error while the broker connection is down.
+func (m *Mqtt) Health() error {
+	if !m.client.client.IsConnectionOpen() {
+		return errors.New("not connected to broker")
+	}
+	return nil
+}
+
+// Close disconnects from the broker, giving in-flight publishes a moment to
+// complete, and saves the publish queue. Only the first call has an effect.
+func (m *Mqtt) Close() {
+	m.closeOnce.Do(func() {
+		close(m.client.done)
+		m.client.client.Disconnect(250)
+		m.client.queue.Close()
+		m.received.Close()
+	})
 }
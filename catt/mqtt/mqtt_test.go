@@ -1,46 +1,145 @@
 package mqtt
 
 import (
-	"fmt"
 	"testing"
 	"time"
 
+	"github.com/catt-ha/catt-go/catt/broker"
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
 	"github.com/catt-ha/catt-go/catt/types"
 )
 
 func TestMqtt(t *testing.T) {
-	cfg := Config{
-		Broker:   "10.8.0.1:1883",
+	b, err := broker.NewBroker(broker.Config{Listen: bustest.FreeAddress(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	m, err := NewMqtt(Config{
+		Broker:   b.Address(),
 		ItemBase: "catt/items",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if err := m.Subscribe("Light_Living_Table_Switch", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+
+	val := types.NewStringValue("ON")
+	if err := m.Publish(types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: "Light_Living_Table_Switch",
+		Value:    &val,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, m.Messages())
+	if msg.Type != types.UpdateMessage || msg.ItemName != "Light_Living_Table_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if s, err := msg.Value.AsString(); err != nil || s != "ON" {
+		t.Fatalf("unexpected value: %v", msg.Value)
+	}
+
+	// Closing again, as the deferred Close does, is a no-op.
+	m.Close()
+}
+
+func TestForget(t *testing.T) {
+	b, err := broker.NewBroker(broker.Config{Listen: bustest.FreeAddress(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	bridge, err := NewMqtt(Config{Broker: b.Address(), ClientId: "bridge"})
+	if err != nil {
+		t.Fatal(err)
 	}
+	defer bridge.Close()
 
-	m, err := NewMqtt(cfg)
+	on := types.NewBoolValue(true)
+	for _, msg := range []types.Message{
+		{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on},
+		{Type: types.MetaMessage, ItemName: "Lamp", Meta: &types.Meta{ValueType: "bool"}},
+		{Type: types.UpdateMessage, ItemName: "Fan", Value: &on},
+	} {
+		if err := bridge.Publish(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bridge.Forget("Lamp"); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewMqtt(Config{Broker: b.Address(), ClientId: "client"})
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Subscribe("+", types.AllSub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the item that is still around is replayed.
+	if msg := bustest.Receive(t, client.Messages()); msg.ItemName != "Fan" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	select {
+	case msg := <-client.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(200 * time.Millisecond):
 	}
+}
 
-	err = m.Subscribe("Light_Living_Table_Switch", types.UpdateSub)
+// A broker that is down at startup is retried, with subscriptions made and
+// messages published in the meantime carried out once it is up.
+func TestBrokerDownAtStartup(t *testing.T) {
+	addr := bustest.FreeAddress(t)
+
+	bridge, err := NewMqtt(Config{Broker: addr, ClientId: "bridge"})
 	if err != nil {
-		panic(err)
+		t.Fatal(err)
 	}
+	defer bridge.Close()
 
-	fmt.Println("subscribed!")
+	if err := bridge.Subscribe("Lamp", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+	on := types.NewBoolValue(true)
+	if err := bridge.Publish(types.Message{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on}); err != nil {
+		t.Fatal(err)
+	}
 
-	msgs := m.Messages()
+	b, err := broker.NewBroker(broker.Config{Listen: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
 
-	go func() {
-		time.Sleep(time.Second * 1)
-		val := types.NewStringValue("ON")
-		m.Publish(types.Message{
-			Type:     types.UpdateMessage,
-			ItemName: "Light_Living_Table_Switch",
-			Value:    &val,
-		})
-		fmt.Println("pubtime: ", time.Now())
-	}()
+	client, err := NewMqtt(Config{Broker: addr, ClientId: "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Subscribe("Lamp", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := bustest.Receive(t, client.Messages()); msg.Type != types.UpdateMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
 
-	for msg := range msgs {
-		fmt.Println("from chan: ", time.Now())
-		fmt.Println(msg.Value.AsString())
+	// The bridge's subscription is in place once its state was flushed.
+	if err := client.Publish(types.Message{Type: types.CommandMessage, ItemName: "Lamp", Value: &on}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := bustest.Receive(t, bridge.Messages()); msg.Type != types.CommandMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
 	}
 }
@@ -0,0 +1,236 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultQueueSize = 1024
+
+// persistDelay is how long changes to the queue are collected before the
+// queue file is rewritten.
+const persistDelay = time.Second
+
+type QueueStats struct {
+	// Queued counts messages that were held back instead of published.
+	Queued uint64
+	// Dropped counts messages evicted because the queue was full.
+	Dropped uint64
+	// Flushed counts held back messages published after reconnecting.
+	Flushed uint64
+	// Pending is the number of messages currently waiting to be flushed.
+	Pending int
+}
+
+type queuedMessage struct {
+	Topic   string `json:"topic"`
+	Payload []byte `json:"payload"`
+}
+
+// retained reports whether messages to a topic are retained state or meta,
+// of which only the latest payload matters.
+func retained(topic string) bool {
+	last := path.Base(topic)
+	return last == "state" || last == "meta"
+}
+
+// publishQueue holds messages in order while the broker is unreachable.
+// Publishing state or meta to a topic that is already queued replaces the
+// payload and moves the topic to the back; commands and errors are all
+// kept.
+type publishQueue struct {
+	mu    sync.Mutex
+	size  int
+	file  string
+	order []*queuedMessage
+	stats QueueStats
+
+	// save is the pending write of the queue file, if any.
+	save *time.Timer
+
+	droppedTotal prometheus.Counter
+	depth        prometheus.Gauge
+}
+
+func newPublishQueue(size int, file string) (*publishQueue, error) {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	q := &publishQueue{
+		size:         size,
+		file:         file,
+		droppedTotal: delivery.DroppedMessages.WithLabelValues("mqtt", "publish"),
+		depth:        delivery.QueueDepth.WithLabelValues("mqtt", "publish"),
+	}
+
+	if file == "" {
+		return q, nil
+	}
+
+	bs, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved []queuedMessage
+	if err := json.Unmarshal(bs, &saved); err != nil {
+		return nil, err
+	}
+
+	for _, msg := range saved {
+		q.push(msg.Topic, msg.Payload)
+	}
+
+	return q, nil
+}
+
+func (q *publishQueue) Push(topic string, payload []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.push(topic, payload)
+	q.stats.Queued++
+	q.persist()
+}
+
+func (q *publishQueue) push(topic string, payload []byte) {
+	if old := q.find(topic); old != nil {
+		q.remove(old)
+	} else if len(q.order) >= q.size {
+		oldest := q.order[0]
+		q.remove(oldest)
+		q.stats.Dropped++
+		q.droppedTotal.Inc()
+		log.WithFields(logrus.Fields{
+			"topic": oldest.Topic,
+		}).Warn("publish queue full, dropping oldest message")
+	}
+
+	q.order = append(q.order, &queuedMessage{Topic: topic, Payload: payload})
+	q.depth.Inc()
+}
+
+// find returns the queued message a new message to topic replaces, if any.
+func (q *publishQueue) find(topic string) *queuedMessage {
+	if !retained(topic) {
+		return nil
+	}
+	for _, msg := range q.order {
+		if msg.Topic == topic {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (q *publishQueue) remove(msg *queuedMessage) {
+	for i, m := range q.order {
+		if m == msg {
+			q.order = append(q.order[:i], q.order[i+1:]...)
+			q.depth.Dec()
+			return
+		}
+	}
+}
+
+func (q *publishQueue) peek() (*queuedMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.order) == 0 {
+		return nil, false
+	}
+	return q.order[0], true
+}
+
+// Flush publishes queued messages in order until the queue is empty or
+// publishing fails. A message is only removed if it was not replaced by a
+// newer payload while it was being published.
+func (q *publishQueue) Flush(publish func(topic string, payload []byte) error) error {
+	for {
+		msg, ok := q.peek()
+		if !ok {
+			return nil
+		}
+
+		if err := publish(msg.Topic, msg.Payload); err != nil {
+			return err
+		}
+
+		q.mu.Lock()
+		q.remove(msg)
+		q.stats.Flushed++
+		q.persist()
+		q.mu.Unlock()
+	}
+}
+
+func (q *publishQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Pending = len(q.order)
+	return stats
+}
+
+// persist schedules writing the queue file, so that a burst of changes
+// results in a single write.
+func (q *publishQueue) persist() {
+	if q.file == "" || q.save != nil {
+		return
+	}
+
+	var save *time.Timer
+	save = time.AfterFunc(persistDelay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.save == save {
+			q.save = nil
+			q.write()
+		}
+	})
+	q.save = save
+}
+
+// Close writes pending changes to the queue file.
+func (q *publishQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.save != nil {
+		q.save.Stop()
+		q.save = nil
+		q.write()
+	}
+}
+
+func (q *publishQueue) write() {
+	saved := make([]queuedMessage, 0, len(q.order))
+	for _, msg := range q.order {
+		saved = append(saved, *msg)
+	}
+
+	bs, err := json.Marshal(saved)
+	if err == nil {
+		tmp := q.file + ".tmp"
+		if err = ioutil.WriteFile(tmp, bs, 0600); err == nil {
+			err = os.Rename(tmp, q.file)
+		}
+	}
+
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"file":  q.file,
+			"error": err,
+		}).Warn("error persisting publish queue")
+	}
+}
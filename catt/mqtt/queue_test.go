@@ -0,0 +1,94 @@
+package mqtt
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPublishQueue(t *testing.T) {
+	dropped := delivery.DroppedMessages.WithLabelValues("mqtt", "publish")
+	droppedBefore := testutil.ToFloat64(dropped)
+
+	file := filepath.Join(t.TempDir(), "queue.json")
+	q, err := newPublishQueue(2, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q.Push("a/state", []byte("1"))
+	q.Push("b/state", []byte("1"))
+	q.Push("a/state", []byte("2"))
+	q.Push("c/state", []byte("1"))
+
+	stats := q.Stats()
+	if stats.Queued != 4 || stats.Dropped != 1 || stats.Pending != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if n := testutil.ToFloat64(dropped) - droppedBefore; n != 1 {
+		t.Fatalf("expected 1 dropped message in the metrics, got %v", n)
+	}
+
+	q.Close()
+	reloaded, err := newPublishQueue(2, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var published []string
+	err = reloaded.Flush(func(topic string, payload []byte) error {
+		published = append(published, topic+"="+string(payload))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(published) != 2 || published[0] != "a/state=2" || published[1] != "c/state=1" {
+		t.Fatalf("unexpected flush order: %v", published)
+	}
+
+	q.Push("d/state", []byte("1"))
+	if err := q.Flush(func(string, []byte) error { return errors.New("offline") }); err == nil {
+		t.Fatal("expected flush error")
+	}
+	if q.Stats().Pending != 2 {
+		t.Fatal("failed flush should keep messages queued")
+	}
+}
+
+// Only state and meta are coalesced; every command is kept, in order.
+func TestPublishQueueCommands(t *testing.T) {
+	q, err := newPublishQueue(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q.Push("Dimmer/command", []byte("INCREASE"))
+	q.Push("Dimmer/state", []byte("10"))
+	q.Push("Dimmer/command", []byte("INCREASE"))
+	q.Push("Dimmer/state", []byte("20"))
+	q.Push("Dimmer/meta", []byte("value_type = \"percent\""))
+
+	var published []string
+	if err := q.Flush(func(topic string, payload []byte) error {
+		published = append(published, topic+"="+string(payload))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Dimmer/command=INCREASE",
+		"Dimmer/command=INCREASE",
+		"Dimmer/state=20",
+		`Dimmer/meta=value_type = "percent"`,
+	}
+	if !reflect.DeepEqual(published, want) {
+		t.Fatalf("unexpected flush order: %v", published)
+	}
+}
@@ -0,0 +1,18 @@
+package mqtt5
+
+import "github.com/catt-ha/catt-go/catt/delivery"
+
+type Config struct {
+	Broker   string `toml:"broker"`
+	ItemBase string `toml:"item_base"`
+	ClientId string `toml:"client_id"`
+	Tls      bool   `toml:"tls"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	// CommandExpiry is the message expiry interval in seconds set on
+	// published commands. Defaults to DefaultCommandExpiry.
+	CommandExpiry uint32 `toml:"command_expiry"`
+
+	delivery.Config
+}
@@ -0,0 +1,681 @@
+package mqtt5
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+var log = logging.Logger("mqtt5")
+
+const (
+	DefaultCommandExpiry = 30
+
+	connectTimeout = 10 * time.Second
+	requestTimeout = 10 * time.Second
+
+	valueTypeProperty = "value_type"
+	originProperty    = "origin"
+	// errorProperty carries the reason a request was rejected.
+	errorProperty = "error"
+)
+
+// pendingResponse is a command received with a response topic, waiting for
+// the outcome of the command.
+type pendingResponse struct {
+	itemName    string
+	topic       string
+	correlation []byte
+	timer       *time.Timer
+}
+
+// Mqtt5 implements types.Bus on top of an MQTT 5 client. The value type and
+// origin of a message travel as user properties, so receivers do not need
+// to guess the type from the payload.
+//
+// A command published with a response topic is answered with the next
+// state update or error of its item, which makes Request usable against
+// any bridge running on this bus. Commands that get neither are forgotten
+// once they expire. Bridges don't say which command a state results from,
+// so this is best-effort: a state update of the item that is published
+// before the command is applied, e.g. by a poll, answers it as well.
+type Mqtt5 struct {
+	cfg      Config
+	cm       *autopaho.ConnectionManager
+	received *delivery.Queue
+
+	// connected tells whether subscriptions and publishes can go to the
+	// broker right away. Subscriptions are made again on connecting and
+	// publishes are queued meanwhile.
+	mu        sync.Mutex
+	connected bool
+	subs      map[string]struct{}
+	responses map[string]*pendingResponse
+	requests  map[string]chan types.Message
+}
+
+func NewMqtt5(cfg Config) (*Mqtt5, error) {
+	if cfg.ClientId == "" {
+		cfg.ClientId = "catt-" + randomId()
+	}
+
+	if cfg.CommandExpiry == 0 {
+		cfg.CommandExpiry = DefaultCommandExpiry
+	}
+
+	proto := "mqtt"
+	if cfg.Tls {
+		proto = "tls"
+	}
+
+	broker := "127.0.0.1:1883"
+	if cfg.Broker != "" {
+		broker = cfg.Broker
+	}
+
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	brokerUrl, err := url.Parse(fmt.Sprintf("%s://%s", proto, broker))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mqtt5{
+		cfg:       cfg,
+		received:  delivery.NewQueue("mqtt5", cfg.Buffer, policy),
+		subs:      make(map[string]struct{}),
+		responses: make(map[string]*pendingResponse),
+		requests:  make(map[string]chan types.Message),
+	}
+
+	connectErr := make(chan error, 1)
+	cm, err := autopaho.NewConnection(context.Background(), autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{brokerUrl},
+		KeepAlive:                     5,
+		CleanStartOnInitialConnection: true,
+		ConnectRetryDelay:             3 * time.Second,
+		ConnectUsername:               cfg.Username,
+		ConnectPassword:               []byte(cfg.Password),
+		OnConnectionUp:                m.onConnectionUp,
+		OnConnectionDown: func() bool {
+			m.mu.Lock()
+			m.connected = false
+			m.mu.Unlock()
+			return true
+		},
+		OnConnectError: func(err error) {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("error connecting to broker")
+
+			select {
+			case connectErr <- err:
+			default:
+			}
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: cfg.ClientId,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				m.onPublish,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.cm = cm
+
+	// A broker that refuses the connection is a configuration error, but
+	// one that is unreachable at startup is retried like a lost
+	// connection, with messages queued in the meantime.
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	up := make(chan error, 1)
+	go func() { up <- cm.AwaitConnection(ctx) }()
+
+	select {
+	case err = <-connectErr:
+	case err = <-up:
+	}
+	if err == nil {
+		return m, nil
+	}
+
+	var connack *autopaho.ConnackError
+	if errors.As(err, &connack) {
+		// Stop the connection manager from retrying in the background.
+		disconnectCtx, disconnectCancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer disconnectCancel()
+		cm.Disconnect(disconnectCtx)
+		m.received.Close()
+		return nil, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"broker": broker,
+		"error":  err,
+	}).Warn("broker unreachable, queueing messages until connected")
+	return m, nil
+}
+
+func randomId() string {
+	bs := make([]byte, 8)
+	rand.Read(bs)
+	return hex.EncodeToString(bs)
+}
+
+func (m *Mqtt5) fullPath(subPath string) string {
+	if m.cfg.ItemBase == "" {
+		return path.Join("catt/items", subPath)
+	}
+	return path.Join(m.cfg.ItemBase, subPath)
+}
+
+func (m *Mqtt5) responseTopic() string {
+	return m.fullPath(path.Join("_responses", m.cfg.ClientId))
+}
+
+// onConnectionUp makes the subscriptions, since the session is not kept by
+// the broker.
+func (m *Mqtt5) onConnectionUp(cm *autopaho.ConnectionManager, _ *paho.Connack) {
+	m.mu.Lock()
+	m.connected = true
+	topics := make([]string, 0, len(m.subs))
+	for topic := range m.subs {
+		topics = append(topics, topic)
+	}
+	m.mu.Unlock()
+
+	if len(topics) == 0 {
+		return
+	}
+
+	go func() {
+		for _, topic := range topics {
+			if err := subscribe(cm, topic); err != nil {
+				log.WithFields(logrus.Fields{
+					"topic": topic,
+					"error": err,
+				}).Warn("error restoring subscription")
+			}
+		}
+	}()
+}
+
+func subscribe(cm *autopaho.ConnectionManager, topic string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := cm.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{
+			{Topic: topic, QoS: 1},
+		},
+	})
+	return err
+}
+
+func (m *Mqtt5) onPublish(pr paho.PublishReceived) (bool, error) {
+	p := pr.Packet
+	props := p.Properties
+	if props == nil {
+		props = &paho.PublishProperties{}
+	}
+
+	if p.Topic == m.responseTopic() {
+		m.deliverResponse(p)
+		return true, nil
+	}
+
+	splitPath := strings.Split(p.Topic, "/")
+	l := len(splitPath)
+	if l < 2 {
+		log.WithFields(logrus.Fields{
+			"path": p.Topic,
+		}).Warn("invalid topic")
+		return false, nil
+	}
+
+	outMsg := types.Message{
+		ItemName: splitPath[l-2],
+		Origin:   props.User.Get(originProperty),
+	}
+
+	// An empty retained state or meta without a value type was cleared by
+	// Forget.
+	last := splitPath[l-1]
+	if len(p.Payload) == 0 && props.User.Get(valueTypeProperty) == "" && (last == "state" || last == "meta") {
+		return true, nil
+	}
+
+	switch last {
+	case "state":
+		outMsg.Type = types.UpdateMessage
+	case "command":
+		outMsg.Type = types.CommandMessage
+		if props.ResponseTopic != "" {
+			m.addResponse(outMsg.ItemName, props)
+		}
+	case "error":
+		outMsg.Type = types.ErrorMessage
+		outMsg.Error = string(p.Payload)
+	case "meta":
+		outMsg.Type = types.MetaMessage
+		meta := new(types.Meta)
+		if err := meta.FromString(string(p.Payload)); err != nil {
+			log.WithFields(logrus.Fields{
+				"meta_string": p.Payload,
+				"error":       err,
+			}).Warn("error deserializing meta")
+			return false, nil
+		}
+		outMsg.Meta = meta
+	default:
+		log.WithFields(logrus.Fields{
+			"path": p.Topic,
+		}).Warn("invalid topic")
+		return false, nil
+	}
+
+	if outMsg.Type == types.UpdateMessage || outMsg.Type == types.CommandMessage {
+		val, err := decodeValue(props, p.Payload)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"path":  p.Topic,
+				"error": err,
+			}).Warn("error decoding value")
+			return false, nil
+		}
+		outMsg.Value = val
+		if props.User.Get(valueTypeProperty) == "" {
+			outMsg.Raw = p.Payload
+		}
+	}
+
+	m.received.Push(outMsg)
+
+	return true, nil
+}
+
+// addResponse registers a request until it is answered or the command
+// expires.
+func (m *Mqtt5) addResponse(itemName string, props *paho.PublishProperties) {
+	expiry := m.cfg.CommandExpiry
+	if props.MessageExpiry != nil {
+		expiry = *props.MessageExpiry
+	}
+
+	key := props.ResponseTopic + "\x00" + string(props.CorrelationData)
+	r := &pendingResponse{
+		itemName:    itemName,
+		topic:       props.ResponseTopic,
+		correlation: props.CorrelationData,
+	}
+	r.timer = time.AfterFunc(time.Duration(expiry)*time.Second, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.responses[key] == r {
+			delete(m.responses, key)
+		}
+	})
+
+	m.mu.Lock()
+	if old, ok := m.responses[key]; ok {
+		old.timer.Stop()
+	}
+	m.responses[key] = r
+	m.mu.Unlock()
+}
+
+// takeResponses removes and returns the pending requests for an item.
+func (m *Mqtt5) takeResponses(itemName string) []*pendingResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pending []*pendingResponse
+	for key, r := range m.responses {
+		if r.itemName != itemName {
+			continue
+		}
+		r.timer.Stop()
+		delete(m.responses, key)
+		pending = append(pending, r)
+	}
+	return pending
+}
+
+func decodeValue(props *paho.PublishProperties, payload []byte) (*types.Value, error) {
+	val := new(types.Value)
+
+	typeStr := props.User.Get(valueTypeProperty)
+	if typeStr == "" {
+		val.FromRaw(payload)
+		return val, nil
+	}
+
+	valueType, err := types.ParseValueType(typeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	*val, err = types.ParseValue(valueType, string(payload))
+	return val, err
+}
+
+func (m *Mqtt5) deliverResponse(p *paho.Publish) {
+	if p.Properties == nil {
+		return
+	}
+
+	id := string(p.Properties.CorrelationData)
+
+	m.mu.Lock()
+	ch, ok := m.requests[id]
+	delete(m.requests, id)
+	m.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if reason := p.Properties.User.Get(errorProperty); reason != "" {
+		ch <- types.Message{
+			Type:   types.ErrorMessage,
+			Error:  reason,
+			Origin: p.Properties.User.Get(originProperty),
+		}
+		return
+	}
+
+	val, err := decodeValue(p.Properties, p.Payload)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("error decoding response")
+		close(ch)
+		return
+	}
+
+	ch <- types.Message{
+		Type:   types.UpdateMessage,
+		Value:  val,
+		Origin: p.Properties.User.Get(originProperty),
+	}
+}
+
+func subTopic(itemName string, subType types.SubType) (string, error) {
+	var last string
+	switch subType {
+	case types.UpdateSub:
+		last = "state"
+	case types.CommandSub:
+		last = "command"
+	case types.MetaSub:
+		last = "meta"
+	case types.AllSub:
+		last = "#"
+	default:
+		return "", fmt.Errorf("invalid sub type: %d", subType)
+	}
+
+	return path.Join(itemName, last), nil
+}
+
+func (m *Mqtt5) Subscribe(itemName string, subType types.SubType) error {
+	subPath, err := subTopic(itemName, subType)
+	if err != nil {
+		return err
+	}
+
+	return m.subscribe(m.fullPath(subPath))
+}
+
+// subscribe records a subscription and makes it if connected.
+func (m *Mqtt5) subscribe(topic string) error {
+	m.mu.Lock()
+	m.subs[topic] = struct{}{}
+	connected := m.connected
+	m.mu.Unlock()
+
+	if !connected {
+		return nil
+	}
+	return subscribe(m.cm, topic)
+}
+
+func (m *Mqtt5) Unsubscribe(itemName string, subType types.SubType) error {
+	subPath, err := subTopic(itemName, subType)
+	if err != nil {
+		return err
+	}
+
+	topic := m.fullPath(subPath)
+
+	m.mu.Lock()
+	delete(m.subs, topic)
+	connected := m.connected
+	m.mu.Unlock()
+
+	if !connected {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err = m.cm.Unsubscribe(ctx, &paho.Unsubscribe{
+		Topics: []string{topic},
+	})
+	return err
+}
+
+func (m *Mqtt5) Publish(message types.Message) error {
+	p, err := m.packet(message)
+	if err != nil {
+		return err
+	}
+
+	if err := m.publish(p); err != nil {
+		return err
+	}
+
+	if message.Type != types.UpdateMessage && message.Type != types.ErrorMessage {
+		return nil
+	}
+
+	for _, r := range m.takeResponses(message.ItemName) {
+		reply := *p
+		props := *p.Properties
+		props.User = append(paho.UserProperties(nil), props.User...)
+		props.ResponseTopic = ""
+		props.CorrelationData = r.correlation
+		if message.Type == types.ErrorMessage {
+			props.User.Add(errorProperty, message.Error)
+		}
+		reply.Topic = r.topic
+		reply.Retain = false
+		reply.Properties = &props
+		if err := m.publish(&reply); err != nil {
+			log.WithFields(logrus.Fields{
+				"topic": r.topic,
+				"error": err,
+			}).Warn("error publishing response")
+		}
+	}
+
+	return nil
+}
+
+// packet builds the publish for a message. State and meta are retained so
+// that new subscribers, like the catt CLI, see them right away.
+func (m *Mqtt5) packet(message types.Message) (*paho.Publish, error) {
+	var last string
+	var val string
+	var err error
+
+	props := &paho.PublishProperties{}
+	props.User.Add(originProperty, m.cfg.ClientId)
+	p := &paho.Publish{
+		Properties: props,
+	}
+
+	switch message.Type {
+	case types.UpdateMessage:
+		last = "state"
+		val, err = message.Value.AsString()
+		props.User.Add(valueTypeProperty, message.Value.Type.String())
+		p.Retain = true
+	case types.CommandMessage:
+		last = "command"
+		val, err = message.Value.AsString()
+		props.User.Add(valueTypeProperty, message.Value.Type.String())
+		expiry := m.cfg.CommandExpiry
+		props.MessageExpiry = &expiry
+		p.QoS = 1
+	case types.MetaMessage:
+		last = "meta"
+		val, err = message.Meta.AsString()
+		props.ContentType = "application/toml"
+		p.Retain = true
+	case types.ErrorMessage:
+		last = "error"
+		val = message.Error
+		props.ContentType = "text/plain"
+	default:
+		return nil, fmt.Errorf("invalid message type: %d", message.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.Topic = m.fullPath(path.Join(message.ItemName, last))
+	p.Payload = []byte(val)
+
+	return p, nil
+}
+
+// publish sends a packet, or queues it while the broker is unreachable.
+func (m *Mqtt5) publish(p *paho.Publish) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	connected := m.connected
+	m.mu.Unlock()
+
+	if !connected {
+		return m.cm.PublishViaQueue(ctx, &autopaho.QueuePublish{Publish: p})
+	}
+	_, err := m.cm.Publish(ctx, p)
+	return err
+}
+
+// Request sends a command for an item and waits for the item's next state
+// update, delivered through the client's response topic. That need not be
+// the outcome of the command, see Mqtt5. A rejected command is returned as
+// an error. Without a deadline on ctx, Request gives up when
+// the command expires.
+func (m *Mqtt5) Request(ctx context.Context, itemName string, value types.Value) (types.Value, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(m.cfg.CommandExpiry)*time.Second)
+		defer cancel()
+	}
+
+	responseTopic := m.responseTopic()
+
+	m.mu.Lock()
+	_, subscribed := m.subs[responseTopic]
+	m.mu.Unlock()
+
+	if !subscribed {
+		if err := m.subscribe(responseTopic); err != nil {
+			return types.Value{}, err
+		}
+	}
+
+	id := randomId()
+	ch := make(chan types.Message, 1)
+
+	m.mu.Lock()
+	m.requests[id] = ch
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.requests, id)
+		m.mu.Unlock()
+	}()
+
+	p, err := m.packet(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: itemName,
+		Value:    &value,
+	})
+	if err != nil {
+		return types.Value{}, err
+	}
+	p.Properties.ResponseTopic = responseTopic
+	p.Properties.CorrelationData = []byte(id)
+
+	if err := m.publish(p); err != nil {
+		return types.Value{}, err
+	}
+
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return types.Value{}, errors.New("invalid response")
+		}
+		if msg.Type == types.ErrorMessage {
+			return types.Value{}, fmt.Errorf("request rejected: %s", msg.Error)
+		}
+		return *msg.Value, nil
+	case <-ctx.Done():
+		return types.Value{}, ctx.Err()
+	}
+}
+
+// Forget clears the retained state and meta of a removed item.
+func (m *Mqtt5) Forget(itemName string) error {
+	for _, last := range []string{"state", "meta"} {
+		if err := m.publish(&paho.Publish{
+			Topic:  m.fullPath(path.Join(itemName, last)),
+			Retain: true,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mqtt5) Messages() <-chan types.Message {
+	return m.received.Messages()
+}
+
+func (m *Mqtt5) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	err := m.cm.Disconnect(ctx)
+	m.received.Close()
+	return err
+}
+
+var _ types.Bus = &Mqtt5{}
+var _ types.Forgetter = &Mqtt5{}
@@ -0,0 +1,332 @@
+package mqtt5
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/broker"
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func runBroker(t *testing.T) string {
+	b, err := broker.NewBroker(broker.Config{Listen: bustest.FreeAddress(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b.Address()
+}
+
+func connect(t *testing.T, cfg Config) *Mqtt5 {
+	m, err := NewMqtt5(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestUserProperties(t *testing.T) {
+	addr := runBroker(t)
+	bridge := connect(t, Config{Broker: addr, ClientId: "bridge"})
+	client := connect(t, Config{Broker: addr, ClientId: "client"})
+
+	if err := bridge.Subscribe("Label", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the value type property, "1.50" would be guessed a number.
+	label := types.NewStringValue("1.50")
+	if err := client.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Label",
+		Value:    &label,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, bridge.Messages())
+	if msg.Type != types.CommandMessage || msg.ItemName != "Label" || msg.Origin != "client" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.Value.Type != types.StringValue || msg.Raw != nil {
+		t.Fatalf("expected a typed string value, got %+v", msg)
+	}
+	if s, _ := msg.Value.AsString(); s != "1.50" {
+		t.Fatalf("unexpected value: %q", s)
+	}
+}
+
+func TestCommandExpiry(t *testing.T) {
+	addr := runBroker(t)
+	client := connect(t, Config{Broker: addr, ClientId: "client", CommandExpiry: 7})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiries := make(chan *uint32, 1)
+	raw := paho.NewClient(paho.ClientConfig{
+		Conn: conn,
+		OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+			func(pr paho.PublishReceived) (bool, error) {
+				expiries <- pr.Packet.Properties.MessageExpiry
+				return true, nil
+			},
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := raw.Connect(ctx, &paho.Connect{ClientID: "raw", CleanStart: true, KeepAlive: 30}); err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Disconnect(&paho.Disconnect{})
+	if _, err := raw.Subscribe(ctx, &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: "catt/items/Lamp/command", QoS: 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	on := types.NewBoolValue(true)
+	if err := client.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Lamp",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case expiry := <-expiries:
+		if expiry == nil || *expiry == 0 || *expiry > 7 {
+			t.Fatalf("unexpected message expiry: %v", expiry)
+		}
+	case <-time.After(bustest.Timeout):
+		t.Fatal("timed out waiting for command")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	addr := runBroker(t)
+	bridge := connect(t, Config{Broker: addr, ClientId: "bridge"})
+	client := connect(t, Config{Broker: addr, ClientId: "client"})
+
+	if err := bridge.Subscribe("Lamp", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		value types.Value
+		err   error
+	}
+	request := func(v types.Value) <-chan result {
+		ch := make(chan result, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), bustest.Timeout)
+			defer cancel()
+			value, err := client.Request(ctx, "Lamp", v)
+			ch <- result{value, err}
+		}()
+		return ch
+	}
+
+	// A rejected command is answered with the error and forgotten.
+	rejected := request(types.NewStringValue("maybe"))
+	bustest.Receive(t, bridge.Messages())
+	if err := bridge.Publish(types.Message{
+		Type:     types.ErrorMessage,
+		ItemName: "Lamp",
+		Error:    "invalid value",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-rejected; r.err == nil || !strings.Contains(r.err.Error(), "invalid value") {
+		t.Fatalf("expected the rejection, got %+v", r)
+	}
+
+	bridge.mu.Lock()
+	pending := len(bridge.responses)
+	bridge.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected no pending responses, got %d", pending)
+	}
+
+	accepted := request(types.NewBoolValue(true))
+	bustest.Receive(t, bridge.Messages())
+	on := types.NewBoolValue(true)
+	if err := bridge.Publish(types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: "Lamp",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := <-accepted
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if b, err := r.value.AsBool(); err != nil || !b {
+		t.Fatalf("unexpected response: %+v", r.value)
+	}
+}
+
+// A request is answered by the next state of its item, whether or not it
+// results from the command.
+func TestRequestBestEffort(t *testing.T) {
+	addr := runBroker(t)
+	bridge := connect(t, Config{Broker: addr, ClientId: "bridge"})
+	client := connect(t, Config{Broker: addr, ClientId: "client"})
+
+	if err := bridge.Subscribe("Lamp", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan types.Value, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), bustest.Timeout)
+		defer cancel()
+		value, err := client.Request(ctx, "Lamp", types.NewBoolValue(true))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- value
+	}()
+	bustest.Receive(t, bridge.Messages())
+
+	// Other items don't answer, but a stale state of the item does.
+	off := types.NewBoolValue(false)
+	for _, name := range []string{"Fan", "Lamp"} {
+		if err := bridge.Publish(types.Message{
+			Type:     types.UpdateMessage,
+			ItemName: name,
+			Value:    &off,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, err := (<-done).AsBool(); err != nil || b {
+		t.Fatalf("expected the stale state as the response, got %v (%v)", b, err)
+	}
+}
+
+func TestResponseExpiry(t *testing.T) {
+	m := &Mqtt5{
+		cfg:       Config{CommandExpiry: 1},
+		responses: make(map[string]*pendingResponse),
+	}
+
+	m.addResponse("Lamp", &paho.PublishProperties{
+		ResponseTopic:   "catt/items/_responses/client",
+		CorrelationData: []byte("1"),
+	})
+
+	deadline := time.Now().Add(bustest.Timeout)
+	for {
+		m.mu.Lock()
+		pending := len(m.responses)
+		m.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the response to expire")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestRetained(t *testing.T) {
+	addr := runBroker(t)
+	bridge := connect(t, Config{Broker: addr, ClientId: "bridge"})
+
+	on := types.NewBoolValue(true)
+	for _, msg := range []types.Message{
+		{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on},
+		{Type: types.MetaMessage, ItemName: "Lamp", Meta: &types.Meta{ValueType: "bool"}},
+		{Type: types.UpdateMessage, ItemName: "Fan", Value: &on},
+		{Type: types.MetaMessage, ItemName: "Fan", Meta: &types.Meta{ValueType: "bool"}},
+	} {
+		if err := bridge.Publish(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bridge.Forget("Lamp"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := connect(t, Config{Broker: addr, ClientId: "client"})
+	if err := client.Subscribe("+", types.AllSub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the item that is still around is replayed.
+	for i := 0; i < 2; i++ {
+		if msg := bustest.Receive(t, client.Messages()); msg.ItemName != "Fan" {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	}
+	select {
+	case msg := <-client.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// A broker that is down at startup is retried, with subscriptions made and
+// messages published in the meantime carried out once it is up.
+func TestBrokerDownAtStartup(t *testing.T) {
+	addr := bustest.FreeAddress(t)
+	bridge := connect(t, Config{Broker: addr, ClientId: "bridge"})
+
+	if err := bridge.Subscribe("Lamp", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+	on := types.NewBoolValue(true)
+	if err := bridge.Publish(types.Message{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := broker.NewBroker(broker.Config{Listen: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	client := connect(t, Config{Broker: addr, ClientId: "client"})
+	if err := client.Subscribe("Lamp", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+	if msg := bustest.Receive(t, client.Messages()); msg.Type != types.UpdateMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	// The bridge's subscription is in place once its state was published.
+	if err := client.Publish(types.Message{Type: types.CommandMessage, ItemName: "Lamp", Value: &on}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := bustest.Receive(t, bridge.Messages()); msg.Type != types.CommandMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestBrokerRefused(t *testing.T) {
+	b, err := broker.NewBroker(broker.Config{
+		Listen:   bustest.FreeAddress(t),
+		Username: "catt",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if m, err := NewMqtt5(Config{Broker: b.Address()}); err == nil {
+		m.Close()
+		t.Fatal("expected connection without credentials to fail")
+	}
+}
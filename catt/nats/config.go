@@ -0,0 +1,20 @@
+package nats
+
+import "github.com/catt-ha/catt-go/catt/delivery"
+
+type Config struct {
+	// Broker is the NATS server URL. Defaults to nats://127.0.0.1:4222.
+	Broker string `toml:"broker"`
+	// ItemBase is the subject prefix for items. Defaults to catt.items.
+	ItemBase string `toml:"item_base"`
+	ClientId string `toml:"client_id"`
+	Tls      bool   `toml:"tls"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	// KvBucket is the JetStream key-value bucket holding the last known
+	// state and meta of every item. Defaults to catt_items.
+	KvBucket string `toml:"kv_bucket"`
+
+	delivery.Config
+}
@@ -0,0 +1,396 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+var log = logging.Logger("nats")
+
+const (
+	defaultItemBase = "catt.items"
+	defaultKvBucket = "catt_items"
+)
+
+// Nats implements types.Bus on NATS. Items live on the subjects
+// <item_base>.<name>.<state|command|meta>. The last state and meta of
+// every item is kept in a JetStream key-value bucket and delivered to new
+// subscribers. Like on MQTT, a command isn't answered: whether it worked
+// shows in the item's next state or error.
+type Nats struct {
+	cfg      Config
+	conn     *natsgo.Conn
+	kv       jetstream.KeyValue
+	received *delivery.Queue
+
+	mu   sync.Mutex
+	subs map[string]*natsgo.Subscription
+}
+
+func NewNats(cfg Config) (*Nats, error) {
+	if cfg.ItemBase == "" {
+		cfg.ItemBase = defaultItemBase
+	}
+	if cfg.KvBucket == "" {
+		cfg.KvBucket = defaultKvBucket
+	}
+
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := "nats"
+	if cfg.Tls {
+		proto = "tls"
+	}
+
+	broker := "127.0.0.1:4222"
+	if cfg.Broker != "" {
+		broker = cfg.Broker
+	}
+	if !strings.Contains(broker, "://") {
+		broker = fmt.Sprintf("%s://%s", proto, broker)
+	}
+
+	opts := []natsgo.Option{
+		natsgo.MaxReconnects(-1),
+		natsgo.ReconnectWait(3 * time.Second),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("lost connection to nats")
+		}),
+	}
+	if cfg.ClientId != "" {
+		opts = append(opts, natsgo.Name(cfg.ClientId))
+	}
+	if cfg.Username != "" {
+		opts = append(opts, natsgo.UserInfo(cfg.Username, cfg.Password))
+	}
+
+	conn, err := natsgo.Connect(broker, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket: cfg.KvBucket,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Nats{
+		cfg:      cfg,
+		conn:     conn,
+		kv:       kv,
+		received: delivery.NewQueue("nats", cfg.Buffer, policy),
+		subs:     make(map[string]*natsgo.Subscription),
+	}, nil
+}
+
+// escapeName makes an item name usable as a subject token and a key-value
+// key by replacing every byte outside [A-Za-z0-9_-] with =XX.
+func escapeName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "=%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func unescapeName(token string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '=' {
+			b.WriteByte(token[i])
+			continue
+		}
+		if i+2 >= len(token) {
+			return "", fmt.Errorf("invalid escape in %s", token)
+		}
+		c, err := strconv.ParseUint(token[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte(c))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// itemToken maps an item name to a subject token. The MQTT style wildcard
+// "+" matches any item.
+func itemToken(itemName string) string {
+	if itemName == "+" {
+		return "*"
+	}
+	return escapeName(itemName)
+}
+
+func (n *Nats) subject(itemName, last string) string {
+	return strings.Join([]string{n.cfg.ItemBase, itemToken(itemName), last}, ".")
+}
+
+func subLast(subType types.SubType) (string, error) {
+	switch subType {
+	case types.UpdateSub:
+		return "state", nil
+	case types.CommandSub:
+		return "command", nil
+	case types.MetaSub:
+		return "meta", nil
+	case types.AllSub:
+		return "*", nil
+	default:
+		return "", fmt.Errorf("invalid sub type: %d", subType)
+	}
+}
+
+func (n *Nats) handle(msg *natsgo.Msg) {
+	tokens := strings.Split(msg.Subject, ".")
+	l := len(tokens)
+	if l < 2 {
+		log.WithFields(logrus.Fields{
+			"subject": msg.Subject,
+		}).Warn("invalid subject")
+		return
+	}
+
+	outMsg, err := decode(tokens[l-2], tokens[l-1], msg.Data)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"subject": msg.Subject,
+			"error":   err,
+		}).Warn("error decoding message")
+		return
+	}
+
+	n.received.Push(outMsg)
+}
+
+func decode(itemToken, last string, payload []byte) (types.Message, error) {
+	itemName, err := unescapeName(itemToken)
+	if err != nil {
+		return types.Message{}, err
+	}
+
+	outMsg := types.Message{
+		ItemName: itemName,
+	}
+
+	val := new(types.Value)
+	meta := new(types.Meta)
+	switch last {
+	case "state":
+		val.FromRaw(payload)
+		outMsg.Type = types.UpdateMessage
+		outMsg.Value = val
+		outMsg.Raw = payload
+	case "command":
+		val.FromRaw(payload)
+		outMsg.Type = types.CommandMessage
+		outMsg.Value = val
+		outMsg.Raw = payload
+	case "error":
+		outMsg.Type = types.ErrorMessage
+		outMsg.Error = string(payload)
+	case "meta":
+		if err := meta.FromString(string(payload)); err != nil {
+			return types.Message{}, err
+		}
+		outMsg.Type = types.MetaMessage
+		outMsg.Meta = meta
+	default:
+		return types.Message{}, fmt.Errorf("invalid message type: %s", last)
+	}
+
+	return outMsg, nil
+}
+
+func (n *Nats) Subscribe(itemName string, subType types.SubType) error {
+	last, err := subLast(subType)
+	if err != nil {
+		return err
+	}
+
+	subject := n.subject(itemName, last)
+
+	n.mu.Lock()
+	_, ok := n.subs[subject]
+	n.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	sub, err := n.conn.Subscribe(subject, n.handle)
+	if err != nil {
+		return err
+	}
+	// Make sure the server knows the subscription before returning, so
+	// that nothing published afterwards is missed.
+	if err := n.conn.Flush(); err != nil {
+		sub.Unsubscribe()
+		return err
+	}
+
+	n.mu.Lock()
+	n.subs[subject] = sub
+	n.mu.Unlock()
+
+	if subType == types.CommandSub {
+		return nil
+	}
+
+	return n.replayLastKnown(itemToken(itemName) + "." + last)
+}
+
+// replayLastKnown delivers the stored state and meta matching a key
+// filter, so new subscribers don't have to wait for the next change.
+func (n *Nats) replayLastKnown(filter string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lister, err := n.kv.ListKeysFiltered(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	for key := range lister.Keys() {
+		entry, err := n.kv.Get(ctx, key)
+		if err != nil {
+			continue
+		}
+
+		tokens := strings.Split(key, ".")
+		if len(tokens) != 2 {
+			continue
+		}
+
+		outMsg, err := decode(tokens[0], tokens[1], entry.Value())
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"key":   key,
+				"error": err,
+			}).Warn("error decoding stored value")
+			continue
+		}
+
+		n.received.Push(outMsg)
+	}
+
+	return nil
+}
+
+func (n *Nats) Unsubscribe(itemName string, subType types.SubType) error {
+	last, err := subLast(subType)
+	if err != nil {
+		return err
+	}
+
+	subject := n.subject(itemName, last)
+
+	n.mu.Lock()
+	sub, ok := n.subs[subject]
+	delete(n.subs, subject)
+	n.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return sub.Unsubscribe()
+}
+
+func (n *Nats) Publish(message types.Message) error {
+	var last string
+	var val string
+	var err error
+	switch message.Type {
+	case types.UpdateMessage:
+		last = "state"
+		val, err = message.Value.AsString()
+	case types.CommandMessage:
+		last = "command"
+		val, err = message.Value.AsString()
+	case types.MetaMessage:
+		last = "meta"
+		val, err = message.Meta.AsString()
+	case types.ErrorMessage:
+		last = "error"
+		val = message.Error
+	default:
+		return fmt.Errorf("invalid message type: %d", message.Type)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	subject := n.subject(message.ItemName, last)
+
+	if err := n.conn.Publish(subject, []byte(val)); err != nil {
+		return err
+	}
+
+	// Commands and errors are only of interest to current subscribers.
+	if message.Type == types.CommandMessage || message.Type == types.ErrorMessage {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = n.kv.Put(ctx, escapeName(message.ItemName)+"."+last, []byte(val))
+	return err
+}
+
+// Forget purges the stored state and meta of a removed item.
+func (n *Nats) Forget(itemName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, last := range []string{"state", "meta"} {
+		if err := n.kv.Purge(ctx, escapeName(itemName)+"."+last); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Nats) Messages() <-chan types.Message {
+	return n.received.Messages()
+}
+
+func (n *Nats) Close() {
+	n.conn.Close()
+	n.received.Close()
+}
+
+var _ types.Bus = &Nats{}
+var _ types.Forgetter = &Nats{}
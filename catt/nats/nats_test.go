@@ -0,0 +1,130 @@
+package nats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+)
+
+func runServer(t *testing.T) *server.Server {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	return natstest.RunServer(&opts)
+}
+
+func TestEscapeName(t *testing.T) {
+	for _, name := range []string{"Living Table_Switch", "a.b*c>d", "=3D", "Küche"} {
+		escaped := escapeName(name)
+		unescaped, err := unescapeName(escaped)
+		if err != nil || unescaped != name {
+			t.Fatalf("%q escaped to %q and back to %q (%v)", name, escaped, unescaped, err)
+		}
+	}
+}
+
+func TestNats(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	bridge, err := NewNats(Config{Broker: s.ClientURL()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	client, err := NewNats(Config{Broker: s.ClientURL()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	on := types.NewBoolValue(true)
+	command := types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Living Table_Switch",
+		Value:    &on,
+	}
+
+	if err := bridge.Subscribe("Living Table_Switch", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Publish(command); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, bridge.Messages())
+	if msg.Type != types.CommandMessage || msg.ItemName != "Living Table_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if err := bridge.Publish(types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: "Living Table_Switch",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Subscribe("+", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = bustest.Receive(t, client.Messages())
+	if msg.Type != types.UpdateMessage || msg.ItemName != "Living Table_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if b, err := msg.Value.AsBool(); err != nil || !b {
+		t.Fatalf("unexpected value: %v", msg.Value)
+	}
+}
+
+func TestForget(t *testing.T) {
+	s := runServer(t)
+	defer s.Shutdown()
+
+	bridge, err := NewNats(Config{Broker: s.ClientURL()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	on := types.NewBoolValue(true)
+	for _, msg := range []types.Message{
+		{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on},
+		{Type: types.MetaMessage, ItemName: "Lamp", Meta: &types.Meta{ValueType: "bool"}},
+		{Type: types.UpdateMessage, ItemName: "Fan", Value: &on},
+	} {
+		if err := bridge.Publish(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bridge.Forget("Lamp"); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewNats(Config{Broker: s.ClientURL()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Subscribe("+", types.AllSub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the item that is still around is replayed.
+	if msg := bustest.Receive(t, client.Messages()); msg.ItemName != "Fan" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	select {
+	case msg := <-client.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
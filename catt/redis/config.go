@@ -0,0 +1,34 @@
+package redis
+
+import "github.com/catt-ha/catt-go/catt/delivery"
+
+type Config struct {
+	// Broker is the Redis server address. Defaults to 127.0.0.1:6379.
+	Broker string `toml:"broker"`
+	// ItemBase prefixes every channel and key. Defaults to catt:items.
+	ItemBase string `toml:"item_base"`
+	ClientId string `toml:"client_id"`
+	Tls      bool   `toml:"tls"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Db       int    `toml:"db"`
+
+	// Streams sends commands through a Redis stream instead of a pub/sub
+	// channel, so they are not lost while the bridge is down.
+	Streams bool `toml:"streams"`
+	// Group is the consumer group reading the command stream. Every bridge
+	// needs its own group. Defaults to the client id.
+	Group string `toml:"group"`
+	// PublishOnly appends commands to the stream without joining the
+	// consumer group, for clients that never receive commands. It can't
+	// be set in the config file.
+	PublishOnly bool `toml:"-"`
+	// StreamLength caps the command stream at about this many entries.
+	// Defaults to 10000.
+	StreamLength int64 `toml:"stream_length"`
+	// PendingTimeout is the number of seconds a command for an item that
+	// isn't subscribed is kept before it is dropped. Defaults to 300.
+	PendingTimeout int `toml:"pending_timeout"`
+
+	delivery.Config
+}
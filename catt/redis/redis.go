@@ -0,0 +1,578 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var log = logging.Logger("redis")
+
+const (
+	defaultItemBase       = "catt:items"
+	defaultStreamLength   = 10000
+	defaultPendingTimeout = 300
+	requestTimeout        = 10 * time.Second
+)
+
+// Redis implements types.Bus on Redis. Messages travel on the pub/sub
+// channels <item_base>:<name>:<state|command|meta>, and the latest state
+// and meta of every item is kept in the hash <item_base> under the fields
+// <name>:state and <name>:meta, so the current state of everything is a
+// single HGETALL away.
+//
+// With streams enabled, commands are appended to the stream
+// <item_base>:commands and read through a consumer group instead. Commands
+// for items that aren't subscribed yet stay pending until they are, or
+// until the pending timeout passes.
+type Redis struct {
+	cfg      Config
+	client   *goredis.Client
+	pubsub   *goredis.PubSub
+	received *delivery.Queue
+	cancel   context.CancelFunc
+
+	// streamMu serializes handling stream entries, so that commands
+	// reclaimed on subscribing can't be overtaken by newer ones.
+	streamMu sync.Mutex
+	mu       sync.Mutex
+	commands map[string]struct{}
+}
+
+func NewRedis(cfg Config) (*Redis, error) {
+	if cfg.ItemBase == "" {
+		cfg.ItemBase = defaultItemBase
+	}
+
+	if cfg.ClientId == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "catt"
+		}
+		cfg.ClientId = host
+	}
+
+	if cfg.Group == "" {
+		cfg.Group = cfg.ClientId
+	}
+
+	if cfg.StreamLength == 0 {
+		cfg.StreamLength = defaultStreamLength
+	}
+
+	if cfg.PendingTimeout == 0 {
+		cfg.PendingTimeout = defaultPendingTimeout
+	}
+
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	broker := "127.0.0.1:6379"
+	if cfg.Broker != "" {
+		broker = cfg.Broker
+	}
+
+	opts := &goredis.Options{
+		Addr:       broker,
+		ClientName: cfg.ClientId,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		DB:         cfg.Db,
+	}
+	if cfg.Tls {
+		opts.TLSConfig = &tls.Config{}
+	}
+
+	client := goredis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	runCtx, runCancel := context.WithCancel(context.Background())
+	r := &Redis{
+		cfg:      cfg,
+		client:   client,
+		pubsub:   client.Subscribe(context.Background()),
+		received: delivery.NewQueue("redis", cfg.Buffer, policy),
+		cancel:   runCancel,
+		commands: make(map[string]struct{}),
+	}
+
+	if cfg.Streams && !cfg.PublishOnly {
+		err := client.XGroupCreateMkStream(ctx, r.stream(), cfg.Group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			r.Close()
+			return nil, err
+		}
+		go r.readStream(runCtx)
+	}
+
+	go r.readPubSub()
+
+	return r, nil
+}
+
+func (r *Redis) channel(itemName, last string) string {
+	return strings.Join([]string{r.cfg.ItemBase, itemName, last}, ":")
+}
+
+func (r *Redis) stream() string {
+	return r.cfg.ItemBase + ":commands"
+}
+
+// pattern builds a channel pattern for PSUBSCRIBE. The MQTT style wildcard
+// "+" matches any item.
+func (r *Redis) pattern(itemName, last string) string {
+	if itemName == "+" {
+		itemName = "*"
+	} else {
+		itemName = globEscape(itemName)
+	}
+	return strings.Join([]string{globEscape(r.cfg.ItemBase), itemName, last}, ":")
+}
+
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func subLast(subType types.SubType) (string, error) {
+	switch subType {
+	case types.UpdateSub:
+		return "state", nil
+	case types.CommandSub:
+		return "command", nil
+	case types.MetaSub:
+		return "meta", nil
+	case types.AllSub:
+		return "*", nil
+	default:
+		return "", fmt.Errorf("invalid sub type: %d", subType)
+	}
+}
+
+// splitKey splits "<name>:<last>" at the last colon, since item names may
+// contain colons themselves.
+func splitKey(key string) (string, string, bool) {
+	i := strings.LastIndex(key, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
+
+func (r *Redis) readPubSub() {
+	prefix := r.cfg.ItemBase + ":"
+	for msg := range r.pubsub.Channel() {
+		itemName, last, ok := splitKey(strings.TrimPrefix(msg.Channel, prefix))
+		if !ok {
+			log.WithFields(logrus.Fields{
+				"channel": msg.Channel,
+			}).Warn("invalid channel")
+			continue
+		}
+
+		outMsg, err := decode(itemName, last, msg.Payload)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"channel": msg.Channel,
+				"error":   err,
+			}).Warn("error decoding message")
+			continue
+		}
+
+		r.received.Push(outMsg)
+	}
+}
+
+// readStream delivers commands from the command stream. Entries that were
+// delivered to this consumer but never acknowledged, e.g. because of a
+// crash or because their item wasn't subscribed, are read first.
+func (r *Redis) readStream(ctx context.Context) {
+	id := "0"
+	for ctx.Err() == nil {
+		streams, err := r.client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+			Group:    r.cfg.Group,
+			Consumer: r.cfg.ClientId,
+			Streams:  []string{r.stream(), id},
+			Count:    16,
+			Block:    5 * time.Second,
+		}).Result()
+		if err == goredis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.WithFields(logrus.Fields{
+					"error": err,
+				}).Warn("error reading command stream")
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		count := 0
+		r.streamMu.Lock()
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				count++
+				r.handleEntry(ctx, entry)
+				if id != ">" {
+					id = entry.ID
+				}
+			}
+		}
+		r.streamMu.Unlock()
+
+		if count == 0 {
+			id = ">"
+		}
+	}
+}
+
+// claimPending delivers the commands left pending for this consumer, now
+// that an item was subscribed. The caller must hold streamMu.
+func (r *Redis) claimPending(ctx context.Context) error {
+	start := "-"
+	for {
+		pending, err := r.client.XPendingExt(ctx, &goredis.XPendingExtArgs{
+			Stream:   r.stream(),
+			Group:    r.cfg.Group,
+			Consumer: r.cfg.ClientId,
+			Start:    start,
+			End:      "+",
+			Count:    64,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, p := range pending {
+			ids = append(ids, p.ID)
+		}
+
+		entries, err := r.client.XClaim(ctx, &goredis.XClaimArgs{
+			Stream:   r.stream(),
+			Group:    r.cfg.Group,
+			Consumer: r.cfg.ClientId,
+			Messages: ids,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			r.handleEntry(ctx, entry)
+		}
+
+		start = nextID(ids[len(ids)-1])
+	}
+}
+
+// nextID returns the stream entry id following id, for paging through
+// ranges without relying on exclusive range support.
+func nextID(id string) string {
+	ms, seq, ok := strings.Cut(id, "-")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil {
+		return id
+	}
+	return ms + "-" + strconv.FormatUint(n+1, 10)
+}
+
+// expired reports whether a stream entry is older than the pending
+// timeout, going by the time in its id.
+func (r *Redis) expired(id string) bool {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return false
+	}
+	timeout := time.Duration(r.cfg.PendingTimeout) * time.Second
+	return time.Since(time.UnixMilli(n)) > timeout
+}
+
+// handleEntry delivers and acknowledges a command if its item is
+// subscribed. Other commands are left pending until they expire, and
+// entries trimmed from the stream meanwhile are dropped. The caller must
+// hold streamMu.
+func (r *Redis) handleEntry(ctx context.Context, entry goredis.XMessage) {
+	if entry.Values == nil {
+		r.ack(ctx, entry.ID)
+		return
+	}
+
+	itemName, _ := entry.Values["item"].(string)
+	value, _ := entry.Values["value"].(string)
+
+	r.mu.Lock()
+	_, subscribed := r.commands[itemName]
+	_, all := r.commands["+"]
+	r.mu.Unlock()
+
+	if !subscribed && !all {
+		if r.expired(entry.ID) {
+			log.WithFields(logrus.Fields{
+				"id":   entry.ID,
+				"item": itemName,
+			}).Warn("dropping command for an item that was never subscribed")
+			r.ack(ctx, entry.ID)
+		}
+		return
+	}
+
+	outMsg, err := decode(itemName, "command", value)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"id":    entry.ID,
+			"error": err,
+		}).Warn("error decoding command")
+	} else {
+		r.received.Push(outMsg)
+	}
+
+	r.ack(ctx, entry.ID)
+}
+
+func (r *Redis) ack(ctx context.Context, id string) {
+	if err := r.client.XAck(ctx, r.stream(), r.cfg.Group, id).Err(); err != nil {
+		log.WithFields(logrus.Fields{
+			"id":    id,
+			"error": err,
+		}).Warn("error acknowledging command")
+	}
+}
+
+func decode(itemName, last, payload string) (types.Message, error) {
+	outMsg := types.Message{
+		ItemName: itemName,
+	}
+
+	val := new(types.Value)
+	meta := new(types.Meta)
+	switch last {
+	case "state":
+		val.FromRaw([]byte(payload))
+		outMsg.Type = types.UpdateMessage
+		outMsg.Value = val
+		outMsg.Raw = []byte(payload)
+	case "command":
+		val.FromRaw([]byte(payload))
+		outMsg.Type = types.CommandMessage
+		outMsg.Value = val
+		outMsg.Raw = []byte(payload)
+	case "error":
+		outMsg.Type = types.ErrorMessage
+		outMsg.Error = string([]byte(payload))
+	case "meta":
+		if err := meta.FromString(payload); err != nil {
+			return types.Message{}, err
+		}
+		outMsg.Type = types.MetaMessage
+		outMsg.Meta = meta
+	default:
+		return types.Message{}, fmt.Errorf("invalid message type: %s", last)
+	}
+
+	return outMsg, nil
+}
+
+func (r *Redis) Subscribe(itemName string, subType types.SubType) error {
+	last, err := subLast(subType)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if subType == types.CommandSub && r.cfg.Streams {
+		if r.cfg.PublishOnly {
+			return errors.New("can't receive commands from the stream when publishing only")
+		}
+		r.streamMu.Lock()
+		defer r.streamMu.Unlock()
+		r.mu.Lock()
+		r.commands[itemName] = struct{}{}
+		r.mu.Unlock()
+		return r.claimPending(ctx)
+	}
+
+	if itemName == "+" || subType == types.AllSub {
+		err = r.pubsub.PSubscribe(ctx, r.pattern(itemName, last))
+	} else {
+		err = r.pubsub.Subscribe(ctx, r.channel(itemName, last))
+	}
+	if err != nil {
+		return err
+	}
+
+	if subType == types.CommandSub {
+		return nil
+	}
+
+	return r.replayLastKnown(ctx, itemName, subType)
+}
+
+// replayLastKnown delivers the stored state and meta for a subscription,
+// so new subscribers don't have to wait for the next change.
+func (r *Redis) replayLastKnown(ctx context.Context, itemName string, subType types.SubType) error {
+	stored, err := r.client.HGetAll(ctx, r.cfg.ItemBase).Result()
+	if err != nil {
+		return err
+	}
+
+	for field, payload := range stored {
+		name, last, ok := splitKey(field)
+		if !ok {
+			continue
+		}
+		if itemName != "+" && name != itemName {
+			continue
+		}
+		if subType == types.UpdateSub && last != "state" || subType == types.MetaSub && last != "meta" {
+			continue
+		}
+
+		outMsg, err := decode(name, last, payload)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"field": field,
+				"error": err,
+			}).Warn("error decoding stored value")
+			continue
+		}
+
+		r.received.Push(outMsg)
+	}
+
+	return nil
+}
+
+func (r *Redis) Unsubscribe(itemName string, subType types.SubType) error {
+	last, err := subLast(subType)
+	if err != nil {
+		return err
+	}
+
+	if subType == types.CommandSub && r.cfg.Streams {
+		r.mu.Lock()
+		delete(r.commands, itemName)
+		r.mu.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if itemName == "+" || subType == types.AllSub {
+		return r.pubsub.PUnsubscribe(ctx, r.pattern(itemName, last))
+	}
+	return r.pubsub.Unsubscribe(ctx, r.channel(itemName, last))
+}
+
+func (r *Redis) Publish(message types.Message) error {
+	var last string
+	var val string
+	var err error
+	switch message.Type {
+	case types.UpdateMessage:
+		last = "state"
+		val, err = message.Value.AsString()
+	case types.CommandMessage:
+		last = "command"
+		val, err = message.Value.AsString()
+	case types.MetaMessage:
+		last = "meta"
+		val, err = message.Meta.AsString()
+	case types.ErrorMessage:
+		last = "error"
+		val = message.Error
+	default:
+		return fmt.Errorf("invalid message type: %d", message.Type)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	if message.Type == types.CommandMessage {
+		if r.cfg.Streams {
+			return r.client.XAdd(ctx, &goredis.XAddArgs{
+				Stream: r.stream(),
+				MaxLen: r.cfg.StreamLength,
+				Approx: true,
+				Values: map[string]interface{}{
+					"item":  message.ItemName,
+					"value": val,
+				},
+			}).Err()
+		}
+		return r.client.Publish(ctx, r.channel(message.ItemName, last), val).Err()
+	}
+
+	// Errors are only of interest to current subscribers.
+	if message.Type == types.ErrorMessage {
+		return r.client.Publish(ctx, r.channel(message.ItemName, last), val).Err()
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, r.cfg.ItemBase, message.ItemName+":"+last, val)
+		pipe.Publish(ctx, r.channel(message.ItemName, last), val)
+		return nil
+	})
+	return err
+}
+
+// Forget deletes the stored state and meta of a removed item.
+func (r *Redis) Forget(itemName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	return r.client.HDel(ctx, r.cfg.ItemBase, itemName+":state", itemName+":meta").Err()
+}
+
+func (r *Redis) Messages() <-chan types.Message {
+	return r.received.Messages()
+}
+
+func (r *Redis) Close() error {
+	r.cancel()
+	r.pubsub.Close()
+	r.received.Close()
+	return r.client.Close()
+}
+
+var _ types.Bus = &Redis{}
+var _ types.Forgetter = &Redis{}
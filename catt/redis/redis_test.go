@@ -0,0 +1,290 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/types"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestRedis(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	bridge, err := NewRedis(Config{Broker: s.Addr(), ClientId: "bridge", Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	client, err := NewRedis(Config{Broker: s.Addr(), ClientId: "client", Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := bridge.Subscribe("Living:Table_Switch", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	on := types.NewBoolValue(true)
+	if err := client.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Living:Table_Switch",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, bridge.Messages())
+	if msg.Type != types.CommandMessage || msg.ItemName != "Living:Table_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	if err := bridge.Publish(types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: "Living:Table_Switch",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.HGet(defaultItemBase, "Living:Table_Switch:state"); got != "ON" {
+		t.Fatalf("unexpected stored state: %q", got)
+	}
+
+	if err := client.Subscribe("+", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = bustest.Receive(t, client.Messages())
+	if msg.Type != types.UpdateMessage || msg.ItemName != "Living:Table_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
+
+func TestPendingCommands(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	bridge, err := NewRedis(Config{Broker: s.Addr(), ClientId: "bridge", Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	client, err := NewRedis(Config{Broker: s.Addr(), ClientId: "client", Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	on := types.NewBoolValue(true)
+	if err := client.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Lamp",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := func() int64 {
+		p, err := bridge.client.XPending(context.Background(), bridge.stream(), "bridge").Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.Count
+	}
+
+	// The bridge reads the command before anything is subscribed, as it
+	// does on startup, and must keep it.
+	deadline := time.Now().Add(bustest.Timeout)
+	for pending() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the command to be read")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if err := bridge.Subscribe("Lamp", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, bridge.Messages())
+	if msg.Type != types.CommandMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if n := pending(); n != 0 {
+		t.Fatalf("expected the command to be acknowledged, %d pending", n)
+	}
+}
+
+func TestExpiredCommands(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	bridge, err := NewRedis(Config{Broker: s.Addr(), ClientId: "bridge", Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	// A command from long ago for an item the bridge doesn't have.
+	ctx := context.Background()
+	if err := bridge.client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: bridge.stream(),
+		ID:     "1-1",
+		Values: map[string]interface{}{"item": "Ghost", "value": "ON"},
+	}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bridge drops it once read instead of keeping it pending.
+	dropped := func() bool {
+		groups, err := bridge.client.XInfoGroups(ctx, bridge.stream()).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(groups) == 1 && groups[0].LastDeliveredID == "1-1" && groups[0].Pending == 0
+	}
+
+	deadline := time.Now().Add(bustest.Timeout)
+	for !dropped() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the command to be dropped")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	select {
+	case msg := <-bridge.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	default:
+	}
+}
+
+func TestPublishOnly(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	bridge, err := NewRedis(Config{Broker: s.Addr(), ClientId: "bridge", Streams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+	if err := bridge.Subscribe("Lamp", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	cli, err := NewRedis(Config{Broker: s.Addr(), ClientId: "cli", Streams: true, PublishOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	if err := cli.Subscribe("Lamp", types.CommandSub); err == nil {
+		t.Fatal("expected publish only clients to refuse command subscriptions")
+	}
+
+	on := types.NewBoolValue(true)
+	if err := cli.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Lamp",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, bridge.Messages())
+	if msg.Type != types.CommandMessage || msg.ItemName != "Lamp" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+
+	groups, err := bridge.client.XInfoGroups(context.Background(), bridge.stream()).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Name != "bridge" {
+		t.Fatalf("expected only the bridge's consumer group, got %+v", groups)
+	}
+}
+
+func TestPointState(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	bridge, err := NewRedis(Config{Broker: s.Addr(), ClientId: "bridge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	client, err := NewRedis(Config{Broker: s.Addr(), ClientId: "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Subscribe("Phone_Location", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+
+	want := types.Point{Lat: 52.5, Lon: 13.4, Alt: 34, HasAlt: true}
+	val := types.NewPointValue(want)
+	if err := bridge.Publish(types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: "Phone_Location",
+		Value:    &val,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, client.Messages())
+	if got, err := msg.Value.AsPoint(); err != nil || msg.Value.Type != types.PointValue || got != want {
+		t.Fatalf("unexpected value: %+v, %v", msg.Value, err)
+	}
+}
+
+func TestForget(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	bridge, err := NewRedis(Config{Broker: s.Addr(), ClientId: "bridge"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bridge.Close()
+
+	on := types.NewBoolValue(true)
+	for _, msg := range []types.Message{
+		{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on},
+		{Type: types.MetaMessage, ItemName: "Lamp", Meta: &types.Meta{ValueType: "bool"}},
+		{Type: types.UpdateMessage, ItemName: "Fan", Value: &on},
+	} {
+		if err := bridge.Publish(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bridge.Forget("Lamp"); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys, _ := s.HKeys(defaultItemBase); len(keys) != 1 || keys[0] != "Fan:state" {
+		t.Fatalf("unexpected stored keys: %v", keys)
+	}
+
+	client, err := NewRedis(Config{Broker: s.Addr(), ClientId: "client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Subscribe("+", types.AllSub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the item that is still around is replayed.
+	if msg := bustest.Receive(t, client.Messages()); msg.ItemName != "Fan" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	select {
+	case msg := <-client.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	reconnectInterval = 3 * time.Second
+	requestTimeout    = 10 * time.Second
+)
+
+type subKey struct {
+	item    string
+	subType types.SubType
+}
+
+// Client implements types.Bus on top of the Catt gRPC service, so that a
+// remote process can follow and control the items of a bridge. Only
+// commands can be published, and commands can't be subscribed to.
+type Client struct {
+	conn     *grpc.ClientConn
+	client   CattClient
+	received *delivery.Queue
+	cancel   context.CancelFunc
+
+	mu   sync.Mutex
+	subs map[subKey]struct{}
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	if cfg.Tls {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if cfg.Username != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(basicAuth{cfg.Username, cfg.Password}))
+	}
+
+	conn, err := grpc.NewClient(cfg.Broker, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		conn:     conn,
+		client:   NewCattClient(conn),
+		received: delivery.NewQueue("grpc", cfg.Buffer, policy),
+		cancel:   cancel,
+		subs:     make(map[subKey]struct{}),
+	}
+
+	go c.watch(ctx)
+
+	return c, nil
+}
+
+func (c *Client) watch(ctx context.Context) {
+	for ctx.Err() == nil {
+		stream, err := c.client.WatchItems(ctx, &WatchItemsRequest{})
+		if err == nil {
+			for {
+				var event *ItemEvent
+				event, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				c.dispatch(event)
+			}
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		log.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("item watch interrupted")
+		time.Sleep(reconnectInterval)
+	}
+	c.received.Close()
+}
+
+func (c *Client) dispatch(event *ItemEvent) {
+	item := event.GetItem()
+
+	switch event.GetType() {
+	case ItemEvent_ADDED:
+		c.deliverItem(item, true)
+	case ItemEvent_CHANGED:
+		c.deliverItem(item, false)
+	}
+}
+
+func (c *Client) deliverItem(item *Item, withMeta bool) {
+	if withMeta && item.GetMeta() != nil {
+		c.deliver(types.Message{
+			Type:     types.MetaMessage,
+			ItemName: item.GetName(),
+			Meta:     fromMeta(item.GetMeta()),
+		})
+	}
+
+	if item.GetValue() != nil {
+		value, err := fromValue(item.GetValue())
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"item":  item.GetName(),
+				"error": err,
+			}).Warn("error decoding value")
+			return
+		}
+		c.deliver(types.Message{
+			Type:     types.UpdateMessage,
+			ItemName: item.GetName(),
+			Value:    &value,
+		})
+	}
+}
+
+func (c *Client) deliver(message types.Message) {
+	subType := types.UpdateSub
+	if message.Type == types.MetaMessage {
+		subType = types.MetaSub
+	}
+
+	c.mu.Lock()
+	matched := false
+	for _, item := range []string{message.ItemName, "+"} {
+		for _, st := range []types.SubType{subType, types.AllSub} {
+			if _, ok := c.subs[subKey{item, st}]; ok {
+				matched = true
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if matched {
+		c.received.Push(message)
+	}
+}
+
+func (c *Client) Subscribe(itemName string, subType types.SubType) error {
+	switch subType {
+	case types.UpdateSub, types.MetaSub, types.AllSub:
+	case types.CommandSub:
+		return errors.New("commands can't be subscribed to over grpc")
+	default:
+		return errors.New("invalid sub type")
+	}
+
+	c.mu.Lock()
+	c.subs[subKey{itemName, subType}] = struct{}{}
+	c.mu.Unlock()
+
+	// Deliver the current state right away, like a retained message.
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var items []*Item
+	if itemName == "+" {
+		resp, err := c.client.ListItems(ctx, &ListItemsRequest{})
+		if err != nil {
+			return err
+		}
+		items = resp.GetItems()
+	} else {
+		// An item that doesn't exist yet is delivered once it is added.
+		item, err := c.client.GetItem(ctx, &GetItemRequest{Name: itemName})
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return err
+		default:
+			items = []*Item{item}
+		}
+	}
+
+	for _, item := range items {
+		c.deliverItem(item, subType != types.UpdateSub)
+	}
+
+	return nil
+}
+
+func (c *Client) Unsubscribe(itemName string, subType types.SubType) error {
+	c.mu.Lock()
+	delete(c.subs, subKey{itemName, subType})
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) Publish(message types.Message) error {
+	if message.Type != types.CommandMessage {
+		return errors.New("only commands can be published over grpc")
+	}
+
+	value, err := toValue(*message.Value)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err = c.client.SendCommand(ctx, &SendCommandRequest{
+		Name:  message.ItemName,
+		Value: value,
+	})
+	return err
+}
+
+func (c *Client) Messages() <-chan types.Message {
+	return c.received.Messages()
+}
+
+func (c *Client) Close() error {
+	c.cancel()
+	return c.conn.Close()
+}
+
+var _ types.Bus = &Client{}
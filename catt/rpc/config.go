@@ -0,0 +1,27 @@
+package rpc
+
+import "github.com/catt-ha/catt-go/catt/delivery"
+
+// Config configures a Client.
+type Config struct {
+	// Broker is the address of the gRPC server.
+	Broker string `toml:"broker"`
+	Tls    bool   `toml:"tls"`
+	// Username and Password are sent with every call if set.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	delivery.Config
+}
+
+// ServerConfig configures the gRPC server of a bridge.
+type ServerConfig struct {
+	// Listen is the address to serve on. The server is disabled if empty.
+	Listen string `toml:"listen"`
+	// Username and Password, if set, are required from every call.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// TlsCert and TlsKey are PEM files that enable TLS.
+	TlsCert string `toml:"tls_cert"`
+	TlsKey  string `toml:"tls_key"`
+}
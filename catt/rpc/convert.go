@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+func toValue(v types.Value) (*Value, error) {
+	switch v.Type {
+	case types.RawValue:
+		raw, err := v.AsRaw()
+		return &Value{Kind: &Value_Raw{Raw: raw}}, err
+	case types.StringValue:
+		s, err := v.AsString()
+		return &Value{Kind: &Value_String_{String_: s}}, err
+	case types.NumberValue:
+		n, err := v.AsNumber()
+		return &Value{Kind: &Value_Number{Number: n}}, err
+	case types.BoolValue:
+		b, err := v.AsBool()
+		return &Value{Kind: &Value_Bool{Bool: b}}, err
+	case types.ColorValue:
+		c, err := v.AsColor()
+		return &Value{Kind: &Value_Color{Color: &Color{H: c.H, S: c.S, V: c.V}}}, err
+	default:
+		s, err := v.AsString()
+		return &Value{Kind: &Value_Typed{Typed: &TypedValue{
+			Type:  v.Type.String(),
+			Value: s,
+		}}}, err
+	}
+}
+
+func fromValue(v *Value) (types.Value, error) {
+	switch kind := v.GetKind().(type) {
+	case *Value_Raw:
+		return types.NewRawValue(kind.Raw), nil
+	case *Value_String_:
+		return types.NewStringValue(kind.String_), nil
+	case *Value_Number:
+		return types.NewNumberValue(kind.Number), nil
+	case *Value_Bool:
+		return types.NewBoolValue(kind.Bool), nil
+	case *Value_Color:
+		return types.NewColorValue(types.Color{
+			H: kind.Color.GetH(),
+			S: kind.Color.GetS(),
+			V: kind.Color.GetV(),
+		}), nil
+	case *Value_Typed:
+		t, err := types.ParseValueType(kind.Typed.GetType())
+		if err != nil {
+			return types.Value{}, err
+		}
+		return types.ParseValue(t, kind.Typed.GetValue())
+	default:
+		return types.Value{}, errors.New("missing value")
+	}
+}
+
+func toMeta(m *types.Meta) *Meta {
+	if m == nil {
+		return nil
+	}
+	return &Meta{
+		Backend:   m.Backend,
+		ValueType: m.ValueType,
+		Ext:       m.Ext,
+		Unit:      m.Unit,
+		ReadOnly:  m.ReadOnly,
+		Min:       m.Min,
+		Max:       m.Max,
+		Step:      m.Step,
+		Enum:      m.Enum,
+		Label:     m.Label,
+		Category:  m.Category,
+		Tags:      m.Tags,
+	}
+}
+
+func fromMeta(m *Meta) *types.Meta {
+	return &types.Meta{
+		Backend:   m.GetBackend(),
+		ValueType: m.GetValueType(),
+		Ext:       m.GetExt(),
+		Unit:      m.GetUnit(),
+		ReadOnly:  m.GetReadOnly(),
+		Min:       m.Min,
+		Max:       m.Max,
+		Step:      m.GetStep(),
+		Enum:      m.GetEnum(),
+		Label:     m.GetLabel(),
+		Category:  m.GetCategory(),
+		Tags:      m.GetTags(),
+	}
+}
+
+// toItem converts an item including its current value. Items whose value
+// can't be read are still returned, without a value.
+func toItem(item types.Item) *Item {
+	out := &Item{
+		Name: item.GetName(),
+		Meta: toMeta(item.GetMeta()),
+	}
+
+	if v, err := item.GetValue(); err == nil {
+		out.Value, _ = toValue(v)
+	}
+
+	return out
+}
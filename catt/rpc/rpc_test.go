@@ -0,0 +1,211 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testItem struct {
+	mu    sync.Mutex
+	name  string
+	value types.Value
+}
+
+func (i *testItem) GetName() string { return i.name }
+
+func (i *testItem) GetMeta() *types.Meta {
+	return &types.Meta{Backend: "test", ValueType: "bool"}
+}
+
+func (i *testItem) GetValue() (types.Value, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.value, nil
+}
+
+func (i *testItem) SetValue(v types.Value) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.value = v
+	return nil
+}
+
+type testRegistry struct {
+	item  *testItem
+	watch chan types.Notification
+
+	mu       sync.Mutex
+	commands []types.Message
+	err      error
+}
+
+func (r *testRegistry) Items() []types.Item { return []types.Item{r.item} }
+
+func (r *testRegistry) Item(name string) types.Item {
+	if name == r.item.name {
+		return r.item
+	}
+	return nil
+}
+
+func (r *testRegistry) Watch() (<-chan types.Notification, func()) {
+	return r.watch, func() {}
+}
+
+// SendCommand records commands and fails them with err. Coercing them is
+// the bridge's job and is tested there.
+func (r *testRegistry) SendCommand(msg types.Message) error {
+	if msg.ItemName != r.item.name {
+		return fmt.Errorf("%w: %s", types.ErrNoSuchItem, msg.ItemName)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, msg)
+	return r.err
+}
+
+func (r *testRegistry) lastCommand(t *testing.T) types.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.commands) == 0 {
+		t.Fatal("expected a command")
+	}
+	return r.commands[len(r.commands)-1]
+}
+
+func TestRpc(t *testing.T) {
+	item := &testItem{name: "Light_Switch", value: types.NewBoolValue(false)}
+	registry := &testRegistry{item: item, watch: make(chan types.Notification, 1)}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	RegisterCattServer(s, NewServer(registry))
+	go s.Serve(l)
+	defer s.Stop()
+
+	c, err := NewClient(Config{Broker: l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Subscribe("+", types.CommandSub); err == nil {
+		t.Fatal("expected command subscription to fail")
+	}
+
+	if err := c.Subscribe("Missing", types.UpdateSub); err != nil {
+		t.Fatalf("expected subscribing to a missing item to succeed, got %v", err)
+	}
+	if err := c.Subscribe("Light_Switch", types.AllSub); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, c.Messages())
+	if msg.Type != types.MetaMessage || msg.Meta.Backend != "test" {
+		t.Fatalf("expected meta, got %+v", msg)
+	}
+	msg = bustest.Receive(t, c.Messages())
+	if msg.Type != types.UpdateMessage || msg.Value.Type != types.BoolValue {
+		t.Fatalf("expected current state, got %+v", msg)
+	}
+
+	on := types.NewBoolValue(true)
+	if err := c.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Light_Switch",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := registry.lastCommand(t); msg.ItemName != "Light_Switch" || msg.Value.Type != types.BoolValue {
+		t.Fatalf("expected a bool command, got %+v", msg)
+	} else if b, _ := msg.Value.AsBool(); !b {
+		t.Fatal("expected command to switch the item on")
+	}
+
+	if err := c.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Missing",
+		Value:    &on,
+	}); err == nil {
+		t.Fatal("expected command to a missing item to fail")
+	}
+
+	item.SetValue(types.NewNumberValue(42))
+	registry.watch <- types.Notification{Type: types.ChangedNotification, Item: item}
+
+	// The watch stream may deliver the initial ADDED events before the
+	// change, so skip ahead to the first update carrying the new value.
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-c.Messages():
+			if msg.Type != types.UpdateMessage {
+				continue
+			}
+			if msg.Value.Type == types.NumberValue {
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for change")
+		}
+	}
+}
+
+func TestRpcAuth(t *testing.T) {
+	item := &testItem{name: "Light_Switch", value: types.NewBoolValue(false)}
+	registry := &testRegistry{item: item, watch: make(chan types.Notification, 1)}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := serverOptions(ServerConfig{Username: "catt", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(opts...)
+	RegisterCattServer(s, NewServer(registry))
+	go s.Serve(l)
+	defer s.Stop()
+
+	for _, cfg := range []Config{
+		{Broker: l.Addr().String()},
+		{Broker: l.Addr().String(), Username: "catt", Password: "wrong"},
+	} {
+		c, err := NewClient(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"+", "Light_Switch"} {
+			if err := c.Subscribe(name, types.UpdateSub); status.Code(err) != codes.Unauthenticated {
+				t.Fatalf("%s: expected unauthenticated error, got %v", name, err)
+			}
+		}
+		c.Close()
+	}
+
+	c, err := NewClient(Config{Broker: l.Addr().String(), Username: "catt", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Subscribe("+", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+	if msg := bustest.Receive(t, c.Messages()); msg.ItemName != "Light_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+}
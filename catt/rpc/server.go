@@ -0,0 +1,170 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+var log = logging.Logger("grpc")
+
+// Server implements the Catt gRPC service over the items of a bridge.
+type Server struct {
+	UnimplementedCattServer
+	registry types.Registry
+}
+
+func NewServer(registry types.Registry) *Server {
+	return &Server{registry: registry}
+}
+
+// serverOptions enables TLS and authentication as configured.
+func serverOptions(cfg ServerConfig) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if cfg.TlsCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TlsCert, cfg.TlsKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	if cfg.Username != "" {
+		auth := basicAuth{cfg.Username, cfg.Password}
+		opts = append(opts, grpc.UnaryInterceptor(auth.unary), grpc.StreamInterceptor(auth.stream))
+	}
+	return opts, nil
+}
+
+// Listen serves the Catt service for a registry on cfg.Listen.
+func Listen(cfg ServerConfig, registry types.Registry) (*grpc.Server, error) {
+	opts, err := serverOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	s := grpc.NewServer(opts...)
+	RegisterCattServer(s, NewServer(registry))
+
+	go func() {
+		if err := s.Serve(l); err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("grpc server stopped")
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *Server) ListItems(ctx context.Context, req *ListItemsRequest) (*ListItemsResponse, error) {
+	items := s.registry.Items()
+	resp := &ListItemsResponse{
+		Items: make([]*Item, 0, len(items)),
+	}
+	for _, item := range items {
+		resp.Items = append(resp.Items, toItem(item))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetItem(ctx context.Context, req *GetItemRequest) (*Item, error) {
+	item := s.registry.Item(req.GetName())
+	if item == nil {
+		return nil, status.Errorf(codes.NotFound, "no such item: %s", req.GetName())
+	}
+	return toItem(item), nil
+}
+
+func (s *Server) SendCommand(ctx context.Context, req *SendCommandRequest) (*SendCommandResponse, error) {
+	value, err := fromValue(req.GetValue())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = s.registry.SendCommand(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: req.GetName(),
+		Value:    &value,
+	})
+	switch {
+	case errors.Is(err, types.ErrNoSuchItem):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, types.ErrInvalidValue):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, types.ErrReadOnly):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return &SendCommandResponse{}, nil
+}
+
+func (s *Server) WatchItems(req *WatchItemsRequest, stream grpc.ServerStreamingServer[ItemEvent]) error {
+	var names map[string]bool
+	if len(req.GetNames()) > 0 {
+		names = make(map[string]bool)
+		for _, name := range req.GetNames() {
+			names[name] = true
+		}
+	}
+	wanted := func(item types.Item) bool {
+		return names == nil || names[item.GetName()]
+	}
+
+	// Watch before listing so that nothing happening in between is missed.
+	notifications, stop := s.registry.Watch()
+	defer stop()
+
+	for _, item := range s.registry.Items() {
+		if !wanted(item) {
+			continue
+		}
+		if err := stream.Send(&ItemEvent{
+			Type: ItemEvent_ADDED,
+			Item: toItem(item),
+		}); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case notification := <-notifications:
+			if !wanted(notification.Item) {
+				continue
+			}
+
+			event := &ItemEvent{
+				Item: toItem(notification.Item),
+			}
+			switch notification.Type {
+			case types.AddedNotification:
+				event.Type = ItemEvent_ADDED
+			case types.RemovedNotification:
+				event.Type = ItemEvent_REMOVED
+			default:
+				event.Type = ItemEvent_CHANGED
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
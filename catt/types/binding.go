@@ -8,6 +8,18 @@ const (
 	RemovedNotification
 )
 
+func (t NotificationType) String() string {
+	switch t {
+	case ChangedNotification:
+		return "changed"
+	case AddedNotification:
+		return "added"
+	case RemovedNotification:
+		return "removed"
+	}
+	return "???"
+}
+
 type Notification struct {
 	Type NotificationType
 	Item Item
@@ -17,3 +29,16 @@ type Binding interface {
 	GetValue(string) Item
 	Notifications() <-chan Notification
 }
+
+// Registry gives access to the items a bridge knows about.
+type Registry interface {
+	Items() []Item
+	Item(string) Item
+	// Watch returns a channel of notifications for all items and a
+	// function that stops watching.
+	Watch() (<-chan Notification, func())
+	// SendCommand sets an item to the value of a command message. Errors
+	// wrap ErrNoSuchItem, ErrReadOnly or ErrInvalidValue if the command was
+	// rejected.
+	SendCommand(Message) error
+}
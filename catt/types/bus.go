@@ -7,8 +7,24 @@ const (
 	UpdateMessage MessageType = iota
 	CommandMessage
 	MetaMessage
+	// ErrorMessage reports a command that couldn't be carried out.
+	ErrorMessage
 )
 
+func (t MessageType) String() string {
+	switch t {
+	case UpdateMessage:
+		return "state"
+	case CommandMessage:
+		return "command"
+	case MetaMessage:
+		return "meta"
+	case ErrorMessage:
+		return "error"
+	}
+	return "???"
+}
+
 const (
 	UpdateSub SubType = iota
 	CommandSub
@@ -21,6 +37,13 @@ type Message struct {
 	ItemName string
 	Value    *Value
 	Meta     *Meta
+	// Origin identifies the publisher of the message, if the bus knows it.
+	Origin string
+	// Raw is the untyped payload Value was guessed from, if any, so that
+	// it can be parsed again as the item's declared type.
+	Raw []byte
+	// Error describes the failure reported by an ErrorMessage.
+	Error string
 }
 
 type Bus interface {
@@ -29,3 +52,10 @@ type Bus interface {
 	Unsubscribe(string, SubType) error
 	Messages() <-chan Message
 }
+
+// Forgetter is implemented by buses that keep the last state and meta of
+// items for new subscribers. The bridge calls Forget when an item is
+// removed, so that it isn't announced anymore.
+type Forgetter interface {
+	Forget(itemName string) error
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	// ErrNoSuchItem is returned for commands to unknown items.
+	ErrNoSuchItem = errors.New("no such item")
+	// ErrInvalidValue is returned for commands whose value doesn't suit
+	// the item.
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrReadOnly is returned for commands to read-only items.
+	ErrReadOnly = errors.New("item is read-only")
+)
+
+// Coerce converts a command value to the type and unit declared by the
+// meta. If the value was guessed from an untyped payload, raw holds that
+// payload, which is parsed as the declared type rather than trusting the
+// guess. Items without a declared type take any value.
+func (m *Meta) Coerce(v Value, raw []byte) (Value, error) {
+	if m == nil {
+		return v, nil
+	}
+
+	var t ValueType
+	if m.ValueType != "" {
+		var err error
+		if t, err = ParseValueType(m.ValueType); err != nil {
+			return v, fmt.Errorf("item declares %v", err)
+		}
+		if raw != nil {
+			if parsed, err := ParseValue(t, string(raw)); err == nil {
+				v = parsed
+			}
+		}
+	}
+
+	if m.Unit != "" {
+		converted, err := v.InUnit(m.Unit)
+		if err != nil {
+			return v, err
+		}
+		v = converted
+	}
+
+	if m.ValueType == "" || v.Type == t {
+		return v, nil
+	}
+	converted, err := v.As(t)
+	if err != nil {
+		return v, fmt.Errorf("can't convert %s to %s: %v", v.Type, t, err)
+	}
+	return converted, nil
+}
+
+// Check verifies that a coerced command value meets the constraints of the
+// meta: the range and step of numbers, percents and quantities, and the
+// vocabulary of enums and strings.
+func (m *Meta) Check(v Value) error {
+	if m == nil {
+		return nil
+	}
+
+	switch v.Type {
+	case NumberValue, PercentValue, QuantityValue:
+		n, err := v.AsNumber()
+		if err != nil {
+			return err
+		}
+		if m.Min != nil && n < *m.Min {
+			return fmt.Errorf("%v is below the minimum of %v", n, *m.Min)
+		}
+		if m.Max != nil && n > *m.Max {
+			return fmt.Errorf("%v is above the maximum of %v", n, *m.Max)
+		}
+		if m.Step > 0 {
+			steps := (n - m.stepBase()) / m.Step
+			if math.Abs(steps-math.Round(steps)) > 1e-9*math.Max(1, math.Abs(steps)) {
+				return fmt.Errorf("%v is not a multiple of the step %v", n, m.Step)
+			}
+		}
+	case EnumValue, StringValue:
+		if len(m.Enum) > 0 && !Vocabulary(m.Enum).Contains(v.string) {
+			return fmt.Errorf("%s is not one of %s", v.string, strings.Join(m.Enum, ", "))
+		}
+	}
+	return nil
+}
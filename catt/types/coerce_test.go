@@ -0,0 +1,58 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCoerce(t *testing.T) {
+	meta := &Meta{ValueType: "number", Unit: "°C"}
+
+	var guessed Value
+	guessed.FromRaw([]byte("70 °F"))
+	v, err := meta.Coerce(guessed, []byte("70 °F"))
+	if n, _ := v.AsNumber(); err != nil || v.Type != NumberValue || math.Abs(n-21.1111) > 1e-3 {
+		t.Fatalf("unexpected value: %+v, %v", v, err)
+	}
+
+	if _, err := meta.Coerce(NewPointValue(Point{}), nil); err == nil {
+		t.Error("expected an error converting a point to a number")
+	}
+	if _, err := (&Meta{ValueType: "nope"}).Coerce(NewNumberValue(1), nil); err == nil {
+		t.Error("expected an error for an invalid declared type")
+	}
+
+	var untyped *Meta
+	if v, err := untyped.Coerce(NewNumberValue(1), nil); err != nil || v.Type != NumberValue {
+		t.Errorf("expected untyped items to take any value, got %+v, %v", v, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	min, max := 10.0, 30.0
+	meta := &Meta{ValueType: "number", Min: &min, Max: &max, Step: 0.5}
+
+	for _, n := range []float64{10, 21.5, 30} {
+		if err := meta.Check(NewNumberValue(n)); err != nil {
+			t.Errorf("unexpected error for %v: %v", n, err)
+		}
+	}
+	for _, n := range []float64{9.5, 30.5, 21.2} {
+		if err := meta.Check(NewNumberValue(n)); err == nil {
+			t.Errorf("expected an error for %v", n)
+		}
+	}
+
+	enum := &Meta{ValueType: "enum", Enum: []string{"HEAT", "COOL"}}
+	if err := enum.Check(NewEnumValue("heat")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := enum.Check(NewEnumValue("FAN")); err == nil {
+		t.Error("expected an error for a symbol outside the vocabulary")
+	}
+
+	var untyped *Meta
+	if err := untyped.Check(NewNumberValue(-1)); err != nil {
+		t.Errorf("expected untyped items to take any value, got %v", err)
+	}
+}
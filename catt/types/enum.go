@@ -0,0 +1,261 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Vocabulary is the set of symbols an enum item accepts.
+type Vocabulary []string
+
+var (
+	// UpDownVocabulary holds the commands of rollershutters and blinds.
+	UpDownVocabulary = Vocabulary{"UP", "DOWN", "STOP", "MOVE"}
+	// IncreaseDecreaseVocabulary holds the commands stepping a percent.
+	IncreaseDecreaseVocabulary = Vocabulary{"INCREASE", "DECREASE"}
+)
+
+// Contains reports whether a symbol is part of the vocabulary, ignoring
+// case.
+func (voc Vocabulary) Contains(symbol string) bool {
+	for _, s := range voc {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse converts a value to an enum value from the vocabulary.
+func (voc Vocabulary) Parse(v Value) (Value, error) {
+	symbol, err := v.AsEnum()
+	if err != nil {
+		return Value{}, err
+	}
+	if !voc.Contains(symbol) {
+		return Value{}, fmt.Errorf("invalid symbol %s, expected one of %s", symbol, strings.Join(voc, ", "))
+	}
+	return NewEnumValue(symbol), nil
+}
+
+// enumPercents gives the percents the position commands stand for. Like a
+// dimmer, a rollershutter's percent is how far it is closed.
+var enumPercents = map[string]float64{
+	"UP":   0,
+	"DOWN": 100,
+	"OFF":  0,
+	"ON":   100,
+}
+
+// NewEnumValue returns a value holding a symbol like UP or STOP. Symbols
+// are upper case.
+func NewEnumValue(symbol string) Value {
+	return Value{
+		Type:   EnumValue,
+		string: strings.ToUpper(symbol),
+	}
+}
+
+// NewPercentValue returns a percent value, clamped to 0–100.
+func NewPercentValue(p float64) Value {
+	return Value{
+		Type:   PercentValue,
+		number: clampPercent(p),
+	}
+}
+
+func clampPercent(p float64) float64 {
+	switch {
+	case p < 0:
+		return 0
+	case p > 100:
+		return 100
+	}
+	return p
+}
+
+// AsEnum converts the value to an enum symbol. Strings must be a single
+// word and bools become ON or OFF.
+func (v Value) AsEnum() (string, error) {
+	switch v.Type {
+	case StringValue:
+		s := strings.TrimSpace(v.string)
+		if s == "" || strings.ContainsAny(s, " \t\r\n") {
+			return "", fmt.Errorf("invalid enum string: %q", v.string)
+		}
+		return strings.ToUpper(s), nil
+	case BoolValue:
+		if v.bool {
+			return "ON", nil
+		}
+		return "OFF", nil
+	case EnumValue:
+		return v.string, nil
+	default:
+		return "", fmt.Errorf("invalid value type: %d", v.Type)
+	}
+}
+
+func (v Value) AsEnumValue() (Value, error) {
+	s, err := v.AsEnum()
+	return NewEnumValue(s), err
+}
+
+// AsPercent converts the value to a percent. Numbers and strings must be
+// within 0–100; strings may end in %. Bools and the position commands UP,
+// DOWN, ON and OFF are 0 or 100.
+func (v Value) AsPercent() (float64, error) {
+	var p float64
+	switch v.Type {
+	case StringValue:
+		s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v.string), "%"))
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			p = n
+		} else if percent, ok := enumPercents[strings.ToUpper(s)]; ok {
+			return percent, nil
+		} else {
+			return 0, fmt.Errorf("invalid percent string: %s", v.string)
+		}
+	case NumberValue:
+		p = v.number
+	case QuantityValue:
+		if v.quantity.Unit != "%" && v.quantity.Unit != "" {
+			return 0, fmt.Errorf("can't convert %s to %%", v.quantity.Unit)
+		}
+		p = v.quantity.Value
+	case BoolValue:
+		if v.bool {
+			return 100, nil
+		}
+		return 0, nil
+	case EnumValue:
+		percent, ok := enumPercents[v.string]
+		if !ok {
+			return 0, fmt.Errorf("%s is not a position", v.string)
+		}
+		return percent, nil
+	case PercentValue:
+		return v.number, nil
+	default:
+		return 0, fmt.Errorf("invalid value type: %d", v.Type)
+	}
+
+	if p < 0 || p > 100 {
+		return 0, fmt.Errorf("percent out of range: %v", p)
+	}
+	return p, nil
+}
+
+func (v Value) AsPercentValue() (Value, error) {
+	p, err := v.AsPercent()
+	return NewPercentValue(p), err
+}
+
+// DefaultStep is how far INCREASE and DECREASE move an item without a Step.
+const DefaultStep = 1
+
+// ApplyStep applies an INCREASE or DECREASE command to a number or percent
+// item, reading the item's value with current. The value moves by the
+// meta's Step to the next multiple of it, counted from Min, and stays
+// within Min and Max, and percents within 0–100. ok is false for other
+// commands and items, which are left to Coerce.
+func (m *Meta) ApplyStep(command Value, current func() (Value, error)) (v Value, ok bool, err error) {
+	if m == nil || command.Type != EnumValue && command.Type != StringValue {
+		return Value{}, false, nil
+	}
+
+	var sign float64
+	switch symbol, _ := command.AsEnum(); symbol {
+	case "INCREASE":
+		sign = 1
+	case "DECREASE":
+		sign = -1
+	default:
+		return Value{}, false, nil
+	}
+
+	t, err := ParseValueType(m.ValueType)
+	if err != nil || t != NumberValue && t != PercentValue {
+		return Value{}, false, nil
+	}
+
+	cur, err := current()
+	if err != nil {
+		return Value{}, true, fmt.Errorf("reading the current value: %v", err)
+	}
+	n, err := cur.AsNumber()
+	if err != nil {
+		return Value{}, true, err
+	}
+
+	if m.Step > 0 {
+		n = m.nextStep(n, sign)
+	} else {
+		n += sign * DefaultStep
+	}
+	// Percents are also bounded by 0 and 100.
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if m.Min != nil {
+		lo = *m.Min
+	}
+	if m.Max != nil {
+		hi = *m.Max
+	}
+	if t == PercentValue {
+		lo, hi = math.Max(lo, 0), math.Min(hi, 100)
+	}
+	if n < lo {
+		n = m.clampStep(lo, 1)
+	}
+	if n > hi {
+		n = m.clampStep(hi, -1)
+	}
+
+	if t == PercentValue {
+		return NewPercentValue(n), true, nil
+	}
+	return NewNumberValue(n), true, nil
+}
+
+// stepTolerance absorbs rounding errors when counting steps.
+const stepTolerance = 1e-9
+
+// stepBase is the value the steps of the meta are counted from.
+func (m *Meta) stepBase() float64 {
+	if m.Min != nil {
+		return *m.Min
+	}
+	return 0
+}
+
+// nextStep returns the multiple of Step above n, or below it for a
+// negative sign, so that values off the grid move onto it.
+// clampStep returns the value on the step grid closest to limit, going
+// into the range in the direction of sign, so that clamped values pass
+// Check.
+func (m *Meta) clampStep(limit, sign float64) float64 {
+	if m.Step <= 0 {
+		return limit
+	}
+	base := m.stepBase()
+	steps := (limit - base) / m.Step
+	if sign > 0 {
+		steps = math.Ceil(steps - stepTolerance)
+	} else {
+		steps = math.Floor(steps + stepTolerance)
+	}
+	return base + steps*m.Step
+}
+
+func (m *Meta) nextStep(n, sign float64) float64 {
+	base := m.stepBase()
+	steps := (n - base) / m.Step
+	if sign > 0 {
+		steps = math.Floor(steps+stepTolerance) + 1
+	} else {
+		steps = math.Ceil(steps-stepTolerance) - 1
+	}
+	return base + steps*m.Step
+}
@@ -0,0 +1,158 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnumValue(t *testing.T) {
+	v, err := UpDownVocabulary.Parse(NewStringValue("stop"))
+	if err != nil || !reflect.DeepEqual(v, NewEnumValue("STOP")) {
+		t.Fatalf("unexpected enum: %+v, %v", v, err)
+	}
+	if _, err := UpDownVocabulary.Parse(NewStringValue("INCREASE")); err == nil {
+		t.Error("expected an error for a symbol outside the vocabulary")
+	}
+	if s, err := NewBoolValue(true).AsEnum(); err != nil || s != "ON" {
+		t.Errorf("unexpected enum for a bool: %q, %v", s, err)
+	}
+
+	cases := map[string]struct {
+		b bool
+		n float64
+	}{
+		"UP":   {false, 0},
+		"DOWN": {true, 100},
+		"ON":   {true, 100},
+	}
+	for symbol, want := range cases {
+		v := NewEnumValue(symbol)
+		if b, err := v.AsBool(); err != nil || b != want.b {
+			t.Errorf("%s: unexpected bool %v, %v", symbol, b, err)
+		}
+		if n, err := v.AsNumber(); err != nil || n != want.n {
+			t.Errorf("%s: unexpected number %v, %v", symbol, n, err)
+		}
+	}
+	if _, err := NewEnumValue("STOP").AsBool(); err == nil {
+		t.Error("expected an error converting STOP to a bool")
+	}
+
+	var raw Value
+	raw.FromRaw([]byte("DOWN"))
+	if !reflect.DeepEqual(raw, NewEnumValue("DOWN")) {
+		t.Errorf("expected FromRaw to detect an enum, got %+v", raw)
+	}
+}
+
+func TestPercentValue(t *testing.T) {
+	for in, want := range map[string]float64{"40": 40, "40 %": 40, "UP": 0, "down": 100} {
+		if p, err := NewStringValue(in).AsPercent(); err != nil || p != want {
+			t.Errorf("%q: got %v, %v", in, p, err)
+		}
+	}
+	for _, v := range []Value{NewNumberValue(120), NewStringValue("-1%"), NewEnumValue("STOP")} {
+		if p, err := v.AsPercent(); err == nil {
+			t.Errorf("%+v: expected an error, got %v", v, p)
+		}
+	}
+	if p, _ := NewPercentValue(150).AsPercent(); p != 100 {
+		t.Errorf("expected NewPercentValue to clamp, got %v", p)
+	}
+	if b, _ := NewPercentValue(0).AsBool(); b {
+		t.Error("expected 0 % to be off")
+	}
+
+	meta := &Meta{ValueType: "percent", Step: 10}
+	steps := []struct {
+		current float64
+		command Value
+		want    float64
+	}{
+		{50, NewEnumValue("INCREASE"), 60},
+		{95, NewEnumValue("INCREASE"), 100},
+		{5, NewStringValue("decrease"), 0},
+	}
+	for _, s := range steps {
+		current := func() (Value, error) { return NewPercentValue(s.current), nil }
+		v, ok, err := meta.ApplyStep(s.command, current)
+		if p, _ := v.AsPercent(); !ok || err != nil || p != s.want {
+			t.Errorf("%v %+v: got %+v, %v, %v", s.current, s.command, v, ok, err)
+		}
+	}
+	for _, command := range []Value{NewEnumValue("DOWN"), NewNumberValue(20)} {
+		if _, ok, _ := meta.ApplyStep(command, nil); ok {
+			t.Errorf("%+v: expected to be left to Coerce", command)
+		}
+	}
+
+	var raw Value
+	raw.FromRaw([]byte("35%"))
+	if !reflect.DeepEqual(raw, NewPercentValue(35)) {
+		t.Errorf("expected FromRaw to detect a percent, got %+v", raw)
+	}
+
+	var out Value
+	if err := json.Unmarshal([]byte(`{"type": "percent", "value": 101}`), &out); err == nil {
+		t.Error("expected an error for a percent out of range")
+	}
+}
+
+// Stepping from a value off the grid lands on it, so that Check accepts it.
+func TestApplyStepOffGrid(t *testing.T) {
+	min, max := 0.0, 22.0
+	meta := &Meta{ValueType: "number", Min: &min, Max: &max, Step: 5}
+	steps := []struct {
+		current float64
+		command string
+		want    float64
+	}{
+		{7, "INCREASE", 10},
+		{7, "DECREASE", 5},
+		{10, "INCREASE", 15},
+		{10, "DECREASE", 5},
+		{19, "INCREASE", 20},
+		{21, "INCREASE", 20},
+		{2, "DECREASE", 0},
+	}
+	for _, s := range steps {
+		current := func() (Value, error) { return NewNumberValue(s.current), nil }
+		v, ok, err := meta.ApplyStep(NewEnumValue(s.command), current)
+		if n, _ := v.AsNumber(); !ok || err != nil || n != s.want {
+			t.Errorf("%v %s: got %+v, %v, %v", s.current, s.command, v, ok, err)
+			continue
+		}
+		if err := meta.Check(v); err != nil {
+			t.Errorf("%v %s: %v", s.current, s.command, err)
+		}
+	}
+}
+
+// Percents are clamped to 0 and 100 as well, which need not be on the grid.
+func TestApplyStepClampPercent(t *testing.T) {
+	below, above := -5.0, 150.0
+	steps := []struct {
+		meta    *Meta
+		current float64
+		command string
+		want    float64
+	}{
+		{&Meta{ValueType: "percent", Step: 30}, 90, "INCREASE", 90},
+		{&Meta{ValueType: "percent", Step: 30}, 60, "INCREASE", 90},
+		{&Meta{ValueType: "percent", Max: &above, Step: 40}, 80, "INCREASE", 80},
+		{&Meta{ValueType: "percent", Min: &below, Step: 10}, 5, "DECREASE", 5},
+		{&Meta{ValueType: "percent", Min: &below, Step: 10}, 15, "DECREASE", 5},
+	}
+	for _, s := range steps {
+		current := func() (Value, error) { return NewPercentValue(s.current), nil }
+		v, ok, err := s.meta.ApplyStep(NewEnumValue(s.command), current)
+		if n, _ := v.AsNumber(); !ok || err != nil || v.Type != PercentValue || n != s.want {
+			t.Errorf("%+v %v %s: got %+v, %v, %v", s.meta, s.current, s.command, v, ok, err)
+			continue
+		}
+		if err := s.meta.Check(v); err != nil {
+			t.Errorf("%+v %v %s: %v", s.meta, s.current, s.command, err)
+		}
+	}
+}
@@ -7,20 +7,44 @@ import (
 )
 
 type Meta struct {
-	Backend   string            `toml:"backend,omitempty"`
-	ValueType string            `toml:"value_type,omitempty"`
-	Ext       map[string]string `toml:"ext"`
+	Backend   string            `toml:"backend,omitempty" json:"backend,omitempty"`
+	ValueType string            `toml:"value_type,omitempty" json:"value_type,omitempty"`
+	Ext       map[string]string `toml:"ext" json:"ext,omitempty"`
+
+	// Unit is the unit the item's quantities are in. Commands in other
+	// units of the same dimension are converted to it.
+	Unit string `toml:"unit,omitempty" json:"unit,omitempty"`
+
+	// ReadOnly items report state but reject commands.
+	ReadOnly bool `toml:"read_only,omitempty" json:"read_only,omitempty"`
+	// Min, Max and Step bound the numeric values of commands, in Unit.
+	// Values must be Min, or 0 without a Min, plus a multiple of Step.
+	Min  *float64 `toml:"min,omitempty" json:"min,omitempty"`
+	Max  *float64 `toml:"max,omitempty" json:"max,omitempty"`
+	Step float64  `toml:"step,omitempty" json:"step,omitempty"`
+	// Enum is the vocabulary of an enum item.
+	Enum []string `toml:"enum,omitempty" json:"enum,omitempty"`
+
+	// Label is a human readable name for the item.
+	Label string `toml:"label,omitempty" json:"label,omitempty"`
+	// Category describes what the item is, e.g. "light" or "temperature".
+	Category string   `toml:"category,omitempty" json:"category,omitempty"`
+	Tags     []string `toml:"tags,omitempty" json:"tags,omitempty"`
 }
 
+// metaFields drops the methods of Meta, so that it can be encoded field by
+// field.
+type metaFields Meta
+
 func (m Meta) AsString() (string, error) {
 	buf := &bytes.Buffer{}
 	enc := toml.NewEncoder(buf)
-	err := enc.Encode(m)
+	err := enc.Encode(metaFields(m))
 	return buf.String(), err
 }
 
 func (m *Meta) FromString(s string) error {
-	_, err := toml.DecodeReader(bytes.NewReader([]byte(s)), m)
+	_, err := toml.DecodeReader(bytes.NewReader([]byte(s)), (*metaFields)(m))
 	return err
 }
 
@@ -0,0 +1,245 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values, colors and metas share one JSON form across the APIs and bus
+// codecs. A value carries its type next to it, so decoding never guesses:
+//
+//	{"type": "bool", "value": true}
+//	{"type": "number", "value": 21.5}
+//	{"type": "string", "value": "hello"}
+//	{"type": "raw", "value": "AAE="}
+//	{"type": "color", "value": {"h": 120, "s": 1, "v": 0.5}}
+//	{"type": "datetime", "value": "2024-05-01T06:12:00+02:00"}
+//	{"type": "quantity", "value": {"value": 21.5, "unit": "°C"}}
+//	{"type": "enum", "value": "STOP"}
+//	{"type": "percent", "value": 40}
+//	{"type": "point", "value": {"lat": 52.5, "lon": 13.4, "alt": 34}}
+//	{"type": "map", "value": {"outdoor": {"type": "number", "value": 3}}}
+//
+// The text form prefixes the value's string form with its type, e.g.
+// "bool:ON", "number:21.5" or "color:120,1,0.5".
+
+type jsonValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	var inner interface{}
+	switch v.Type {
+	case RawValue:
+		inner = v.raw
+	case StringValue:
+		inner = v.string
+	case NumberValue:
+		inner = v.number
+	case BoolValue:
+		inner = v.bool
+	case ColorValue:
+		inner = v.color
+	case DateTimeValue:
+		inner = v.time.Format(time.RFC3339Nano)
+	case QuantityValue:
+		inner = v.quantity
+	case EnumValue:
+		inner = v.string
+	case PercentValue:
+		inner = v.number
+	case PointValue:
+		inner = v.point
+	case MapValue:
+		inner = v.fields
+	default:
+		return nil, fmt.Errorf("invalid value type: %d", v.Type)
+	}
+
+	raw, err := json.Marshal(inner)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue{Type: v.Type.String(), Value: raw})
+}
+
+// UnmarshalValue decodes the JSON form of a value like UnmarshalJSON. For
+// a bare JSON string it also returns the string as the raw payload the
+// value was guessed from, to be used as a Message's Raw.
+func UnmarshalValue(data []byte) (Value, []byte, error) {
+	var v Value
+	if string(bytes.TrimSpace(data)) == "null" {
+		return v, nil, fmt.Errorf("missing value")
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v.FromRaw([]byte(s))
+		return v, []byte(s), nil
+	}
+	err := v.UnmarshalJSON(data)
+	return v, nil, err
+}
+
+// UnmarshalJSON decodes the JSON form of a value. Values of any type may
+// also be given in their string form, e.g. {"type": "bool", "value": "ON"},
+// and a bare JSON string is interpreted like a raw payload by FromRaw.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v.FromRaw([]byte(s))
+		return nil
+	}
+
+	var jv jsonValue
+	if err := json.Unmarshal(data, &jv); err != nil {
+		return err
+	}
+	t, err := ParseValueType(jv.Type)
+	if err != nil {
+		return err
+	}
+	if len(jv.Value) == 0 {
+		return fmt.Errorf("missing %s value", t)
+	}
+
+	if t != StringValue && t != RawValue && json.Unmarshal(jv.Value, &s) == nil {
+		return v.parseText(t, s)
+	}
+
+	out := Value{Type: t}
+	switch t {
+	case RawValue:
+		err = json.Unmarshal(jv.Value, &out.raw)
+	case StringValue:
+		err = json.Unmarshal(jv.Value, &out.string)
+	case NumberValue:
+		err = json.Unmarshal(jv.Value, &out.number)
+	case BoolValue:
+		err = json.Unmarshal(jv.Value, &out.bool)
+	case ColorValue:
+		err = json.Unmarshal(jv.Value, &out.color)
+	case DateTimeValue:
+		// Numbers are seconds since the Unix epoch.
+		var n float64
+		if err = json.Unmarshal(jv.Value, &n); err == nil {
+			out, err = NewNumberValue(n).AsDateTimeValue()
+		}
+	case QuantityValue:
+		err = json.Unmarshal(jv.Value, &out.quantity)
+	case PercentValue:
+		var n float64
+		if err = json.Unmarshal(jv.Value, &n); err == nil {
+			out, err = NewNumberValue(n).AsPercentValue()
+		}
+	case EnumValue:
+		err = fmt.Errorf("expected a string")
+	case PointValue:
+		err = json.Unmarshal(jv.Value, &out.point)
+	case MapValue:
+		err = json.Unmarshal(jv.Value, &out.fields)
+		if err == nil && out.fields == nil {
+			err = fmt.Errorf("expected an object")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value: %v", t, err)
+	}
+	*v = out
+	return nil
+}
+
+func (v Value) MarshalText() ([]byte, error) {
+	var s string
+	var err error
+	if v.Type == ColorValue {
+		var text []byte
+		text, err = v.color.MarshalText()
+		s = string(text)
+	} else {
+		s, err = v.AsString()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(v.Type.String() + ":" + s), nil
+}
+
+func (v *Value) UnmarshalText(text []byte) error {
+	typ, s, ok := strings.Cut(string(text), ":")
+	if !ok {
+		return fmt.Errorf("missing value type: %q", text)
+	}
+	t, err := ParseValueType(typ)
+	if err != nil {
+		return err
+	}
+	return v.parseText(t, s)
+}
+
+// parseText parses the string form of a value of type t.
+func (v *Value) parseText(t ValueType, s string) error {
+	out, err := ParseValue(t, s)
+	if err != nil {
+		return err
+	}
+	*v = out
+	return nil
+}
+
+type jsonColor struct {
+	H float64 `json:"h"`
+	S float64 `json:"s"`
+	V float64 `json:"v"`
+}
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonColor(c))
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var jc jsonColor
+	if err := json.Unmarshal(data, &jc); err != nil {
+		return err
+	}
+	*c = Color(jc)
+	return nil
+}
+
+// MarshalText encodes a color as "H,S,V".
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatFloat(c.H, 'g', -1, 64) + "," +
+		strconv.FormatFloat(c.S, 'g', -1, 64) + "," +
+		strconv.FormatFloat(c.V, 'g', -1, 64)), nil
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	parsed, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func (m Meta) MarshalJSON() ([]byte, error) {
+	return json.Marshal(metaFields(m))
+}
+
+func (m *Meta) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*metaFields)(m))
+}
+
+// MarshalText encodes a meta in the TOML form used on the buses.
+func (m Meta) MarshalText() ([]byte, error) {
+	s, err := m.AsString()
+	return []byte(s), err
+}
+
+func (m *Meta) UnmarshalText(text []byte) error {
+	return m.FromString(string(text))
+}
@@ -0,0 +1,169 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestValueJSON(t *testing.T) {
+	values := []Value{
+		NewRawValue([]byte{0, 1}),
+		NewStringValue("ON"),
+		NewNumberValue(21.5),
+		NewBoolValue(true),
+		NewColorValue(Color{H: 120, S: 1, V: 0.5}),
+		NewQuantityValue(Quantity{21.5, "°C"}),
+		NewEnumValue("STOP"),
+		NewPercentValue(40),
+		NewPointValue(Point{Lat: 52.5, Lon: 13.4, Alt: 34, HasAlt: true}),
+	}
+
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out Value
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if !reflect.DeepEqual(v, out) {
+			t.Errorf("%s: got %+v, want %+v", data, out, v)
+		}
+
+		text, err := v.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = Value{}
+		if err := out.UnmarshalText(text); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		if !reflect.DeepEqual(v, out) {
+			t.Errorf("%s: got %+v, want %+v", text, out, v)
+		}
+	}
+
+	data, _ := json.Marshal(NewColorValue(Color{H: 120, S: 1, V: 0.5}))
+	if string(data) != `{"type":"color","value":{"h":120,"s":1,"v":0.5}}` {
+		t.Errorf("unexpected color JSON: %s", data)
+	}
+}
+
+func TestValueJSONLenient(t *testing.T) {
+	cases := map[string]Value{
+		`{"type": "bool", "value": "ON"}`:        NewBoolValue(true),
+		`{"type": "number", "value": "3"}`:       NewNumberValue(3),
+		`{"type": "color", "value": "10,0.5,1"}`: NewColorValue(Color{H: 10, S: 0.5, V: 1}),
+		`{"type": "string", "value": "ON"}`:      NewStringValue("ON"),
+		`"OFF"`:                                  NewBoolValue(false),
+	}
+	for in, want := range cases {
+		var v Value
+		if err := json.Unmarshal([]byte(in), &v); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("%s: got %+v, want %+v", in, v, want)
+		}
+	}
+
+	for _, in := range []string{
+		`{"type": "bool", "value": "maybe"}`,
+		`{"type": "bool"}`,
+		`{"type": "date", "value": 1}`,
+		`{"type": "number", "value": true}`,
+	} {
+		var v Value
+		if err := json.Unmarshal([]byte(in), &v); err == nil {
+			t.Errorf("%s: expected an error, got %+v", in, v)
+		}
+	}
+}
+
+func TestMetaJSON(t *testing.T) {
+	m := Meta{Backend: "hue", ValueType: "bool", Ext: map[string]string{"id": "1"}}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"backend":"hue","value_type":"bool","ext":{"id":"1"}}` {
+		t.Errorf("unexpected meta JSON: %s", data)
+	}
+	var out Meta
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, out) {
+		t.Errorf("got %+v, want %+v", out, m)
+	}
+
+	text, err := m.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = Meta{}
+	if err := out.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, out) {
+		t.Errorf("got %+v, want %+v", out, m)
+	}
+}
+
+func TestColorString(t *testing.T) {
+	v := NewColorValue(Color{H: 120, S: 1, V: 0.5})
+	s, err := v.AsString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ParseValue(ColorValue, s)
+	if err != nil {
+		t.Fatalf("%q: %v", s, err)
+	}
+	if !reflect.DeepEqual(v, out) {
+		t.Errorf("got %+v, want %+v", out, v)
+	}
+}
+
+// The compact color form must mean the same on every transport for items
+// declaring colors. Only the table form is guessed to be a color.
+func TestColorTextForms(t *testing.T) {
+	want := NewColorValue(Color{H: 120, S: 1, V: 0.5})
+	toml, err := want.AsString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"120,1,0.5", " 120, 1, 0.5", toml} {
+		var fromJSON Value
+		if err := json.Unmarshal([]byte(`{"type":"color","value":`+strconv.Quote(s)+`}`), &fromJSON); err != nil {
+			t.Fatalf("json %q: %v", s, err)
+		}
+
+		parsed, err := ParseValue(ColorValue, s)
+		if err != nil {
+			t.Fatalf("parse %q: %v", s, err)
+		}
+
+		var guessed Value
+		guessed.FromRaw([]byte(s))
+		if (guessed.Type == ColorValue) != (s == toml) {
+			t.Errorf("raw %q: unexpectedly guessed %v", s, guessed.Type)
+		}
+
+		coerced, err := (&Meta{ValueType: "color"}).Coerce(guessed, []byte(s))
+		if err != nil {
+			t.Fatalf("coerce %q: %v", s, err)
+		}
+
+		for name, got := range map[string]Value{"json": fromJSON, "parse": parsed, "coerce": coerced} {
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %q: got %+v, want %+v", name, s, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NewMapValue returns a value holding named sub-values, e.g. the readings
+// of a weather station. Sub-values may be maps themselves.
+func NewMapValue(fields map[string]Value) Value {
+	return Value{
+		Type:   MapValue,
+		fields: copyFields(fields),
+	}
+}
+
+func copyFields(fields map[string]Value) map[string]Value {
+	out := make(map[string]Value, len(fields))
+	for k, v := range fields {
+		out[k] = v
+	}
+	return out
+}
+
+// AsMap converts the value to its named sub-values. Strings are parsed as
+// a JSON object of values.
+func (v Value) AsMap() (map[string]Value, error) {
+	switch v.Type {
+	case StringValue:
+		var fields map[string]Value
+		if err := json.Unmarshal([]byte(v.string), &fields); err != nil {
+			return nil, fmt.Errorf("invalid map string: %v", err)
+		}
+		if fields == nil {
+			return nil, fmt.Errorf("invalid map string: %s", v.string)
+		}
+		return fields, nil
+	case MapValue:
+		return copyFields(v.fields), nil
+	default:
+		return nil, fmt.Errorf("invalid value type: %d", v.Type)
+	}
+}
+
+func (v Value) AsMapValue() (Value, error) {
+	fields, err := v.AsMap()
+	return NewMapValue(fields), err
+}
+
+// Path returns a sub-value by its dot-separated path, e.g. "temp.outdoor".
+func (v Value) Path(path string) (Value, error) {
+	names := strings.Split(path, ".")
+	cur := v
+	for i, name := range names {
+		if cur.Type != MapValue {
+			if i == 0 {
+				return Value{}, fmt.Errorf("invalid value type: %d", cur.Type)
+			}
+			return Value{}, fmt.Errorf("%s is not a map", strings.Join(names[:i], "."))
+		}
+		next, ok := cur.fields[name]
+		if !ok {
+			return Value{}, fmt.Errorf("no such field: %s", strings.Join(names[:i+1], "."))
+		}
+		cur = next
+	}
+	return cur, nil
+}
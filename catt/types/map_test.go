@@ -0,0 +1,70 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func weather() Value {
+	return NewMapValue(map[string]Value{
+		"temp": NewMapValue(map[string]Value{
+			"outdoor": NewQuantityValue(Quantity{3.5, "°C"}),
+			"indoor":  NewQuantityValue(Quantity{21, "°C"}),
+		}),
+		"raining": NewBoolValue(true),
+	})
+}
+
+func TestMapPath(t *testing.T) {
+	v := weather()
+
+	out, err := v.Path("temp.outdoor")
+	if err != nil || !reflect.DeepEqual(out, NewQuantityValue(Quantity{3.5, "°C"})) {
+		t.Fatalf("unexpected value: %+v, %v", out, err)
+	}
+	for _, path := range []string{"temp.attic", "raining.today", "temp.outdoor.x", ""} {
+		if _, err := v.Path(path); err == nil {
+			t.Errorf("%q: expected an error", path)
+		}
+	}
+	if _, err := NewNumberValue(1).Path("x"); err == nil {
+		t.Error("expected an error for a path into a number")
+	}
+}
+
+func TestMapString(t *testing.T) {
+	v := weather()
+
+	s, err := v.AsString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ParseValue(MapValue, s)
+	if err != nil || !reflect.DeepEqual(out, v) {
+		t.Fatalf("%s: got %+v, %v", s, out, err)
+	}
+
+	var raw Value
+	raw.FromRaw([]byte(s))
+	if !reflect.DeepEqual(raw, v) {
+		t.Errorf("expected FromRaw to detect a map, got %+v", raw)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Value
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, v) {
+		t.Errorf("%s: got %+v, %v", data, decoded, err)
+	}
+
+	// Sub-values given as plain strings are read like raw payloads.
+	if err := json.Unmarshal([]byte(`{"type": "map", "value": {"power": "1200 W"}}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := decoded.Path("power"); p.Type != QuantityValue {
+		t.Errorf("unexpected sub-value: %+v", p)
+	}
+}
@@ -0,0 +1,108 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+// Point is a geographic location in degrees, with an optional altitude in
+// meters.
+type Point struct {
+	Lat    float64
+	Lon    float64
+	Alt    float64
+	HasAlt bool
+}
+
+// ParsePoint parses "lat,lon" or "lat,lon,alt".
+func ParsePoint(s string) (Point, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Point{}, fmt.Errorf("invalid point: %q", s)
+	}
+
+	var f [3]float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("invalid point: %q", s)
+		}
+		f[i] = n
+	}
+
+	p := Point{Lat: f[0], Lon: f[1], Alt: f[2], HasAlt: len(parts) == 3}
+	return p, p.validate()
+}
+
+func (p Point) validate() error {
+	if p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("latitude out of range: %v", p.Lat)
+	}
+	if p.Lon < -180 || p.Lon > 180 {
+		return fmt.Errorf("longitude out of range: %v", p.Lon)
+	}
+	return nil
+}
+
+func (p Point) String() string {
+	s := strconv.FormatFloat(p.Lat, 'g', -1, 64) + "," + strconv.FormatFloat(p.Lon, 'g', -1, 64)
+	if p.HasAlt {
+		s += "," + strconv.FormatFloat(p.Alt, 'g', -1, 64)
+	}
+	return s
+}
+
+// Distance returns the great-circle distance to another point in meters,
+// ignoring altitude.
+func (p Point) Distance(to Point) float64 {
+	lat1, lat2 := p.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (to.Lon - p.Lon) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Within reports whether the point lies in the circular geofence of the
+// given radius in meters around center.
+func (p Point) Within(center Point, radius float64) bool {
+	return p.Distance(center) <= radius
+}
+
+type jsonPoint struct {
+	Lat float64  `json:"lat"`
+	Lon float64  `json:"lon"`
+	Alt *float64 `json:"alt,omitempty"`
+}
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	jp := jsonPoint{Lat: p.Lat, Lon: p.Lon}
+	if p.HasAlt {
+		jp.Alt = &p.Alt
+	}
+	return json.Marshal(jp)
+}
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var jp jsonPoint
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+
+	out := Point{Lat: jp.Lat, Lon: jp.Lon}
+	if jp.Alt != nil {
+		out.Alt, out.HasAlt = *jp.Alt, true
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	*p = out
+	return nil
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParsePoint(t *testing.T) {
+	cases := map[string]Point{
+		"52.5,13.4":       {Lat: 52.5, Lon: 13.4},
+		" 52.5, 13.4, 34": {Lat: 52.5, Lon: 13.4, Alt: 34, HasAlt: true},
+	}
+	for in, want := range cases {
+		p, err := ParsePoint(in)
+		if err != nil || p != want {
+			t.Errorf("%q: got %+v, %v", in, p, err)
+		}
+	}
+	for _, in := range []string{"52.5", "52.5,x", "91,0", "0,181", "1,2,3,4"} {
+		if _, err := ParsePoint(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+	if s := (Point{Lat: 52.5, Lon: 13.4, Alt: 34, HasAlt: true}).String(); s != "52.5,13.4,34" {
+		t.Errorf("unexpected string: %q", s)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	berlin := Point{Lat: 52.5200, Lon: 13.4050}
+	paris := Point{Lat: 48.8566, Lon: 2.3522}
+
+	if d := berlin.Distance(paris); math.Abs(d-877.5e3) > 2e3 {
+		t.Errorf("unexpected distance from Berlin to Paris: %v m", d)
+	}
+	if d := berlin.Distance(berlin); d != 0 {
+		t.Errorf("unexpected distance to itself: %v m", d)
+	}
+
+	home := Point{Lat: 52.5200, Lon: 13.4050}
+	if !(Point{Lat: 52.5205, Lon: 13.4050}).Within(home, 100) {
+		t.Error("expected a point 55 m away to be within 100 m")
+	}
+	if (Point{Lat: 52.5300, Lon: 13.4050}).Within(home, 100) {
+		t.Error("expected a point 1.1 km away to be outside 100 m")
+	}
+}
+
+func TestPointValue(t *testing.T) {
+	v, err := ParseValue(PointValue, "52.5,13.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) != `{"type":"point","value":{"lat":52.5,"lon":13.4}}` {
+		t.Fatalf("unexpected JSON: %s, %v", data, err)
+	}
+
+	var out Value
+	if err := json.Unmarshal([]byte(`{"type": "point", "value": {"lat": 52.5, "lon": 13.4, "alt": 34}}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := out.AsPoint(); !p.HasAlt || p.Alt != 34 {
+		t.Errorf("unexpected point: %+v", p)
+	}
+	if err := json.Unmarshal([]byte(`{"type": "point", "value": {"lat": 95, "lon": 0}}`), &out); err == nil {
+		t.Error("expected an error for a latitude out of range")
+	}
+}
+
+// Points are guessed from their text form, which must not be mistaken for
+// a compact color or a number with thousands separators.
+func TestPointFromRaw(t *testing.T) {
+	for _, p := range []Point{
+		{Lat: 52.5, Lon: 13.4},
+		{Lat: 52.5, Lon: 13.4, Alt: 34, HasAlt: true},
+	} {
+		s, err := NewPointValue(p).AsString()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var guessed Value
+		guessed.FromRaw([]byte(s))
+		if got, _ := guessed.AsPoint(); guessed.Type != PointValue || got != p {
+			t.Errorf("%q: got %+v", s, guessed)
+		}
+	}
+
+	for s, isPoint := range map[string]bool{
+		"52, 13":    true,
+		"52, 13, 4": true,
+		"1,000":     false,
+		"1,000.5":   false,
+		"1,000,000": false,
+		"52,13":     false,
+	} {
+		var guessed Value
+		guessed.FromRaw([]byte(s))
+		if (guessed.Type == PointValue) != isPoint {
+			t.Errorf("%q: unexpectedly guessed %v", s, guessed.Type)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a number with a unit, e.g. 21.5 °C or 1200 W.
+type Quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// unit describes how to convert a unit to the base unit of its dimension:
+// base = value*scale + offset.
+type unit struct {
+	dimension string
+	scale     float64
+	offset    float64
+}
+
+var units = map[string]unit{
+	"°C":  {"temperature", 1, 0},
+	"°F":  {"temperature", 5.0 / 9, -32 * 5.0 / 9},
+	"K":   {"temperature", 1, -273.15},
+	"W":   {"power", 1, 0},
+	"kW":  {"power", 1e3, 0},
+	"Wh":  {"energy", 1, 0},
+	"kWh": {"energy", 1e3, 0},
+}
+
+// ParseQuantity parses a number followed by an optional unit, with or
+// without a space in between.
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	i := numberEnd(s)
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	unit := strings.TrimSpace(s[i:])
+	if err != nil || strings.IndexAny(unit, "0123456789+-.") == 0 {
+		return Quantity{}, fmt.Errorf("invalid quantity: %s", s)
+	}
+	return Quantity{Value: n, Unit: unit}, nil
+}
+
+// numberEnd returns the length of the number s starts with. An e or E only
+// starts an exponent if a digit or a sign follows, so that units like
+// "eggs" are not taken for one.
+func numberEnd(s string) int {
+	isDigit := func(i int) bool { return i < len(s) && s[i] >= '0' && s[i] <= '9' }
+
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	for isDigit(i) || i < len(s) && s[i] == '.' {
+		i++
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if isDigit(j) {
+			for i = j; isDigit(i); i++ {
+			}
+		}
+	}
+	return i
+}
+
+// KnownUnit reports whether a unit can be converted to others.
+func KnownUnit(unit string) bool {
+	_, ok := units[unit]
+	return ok
+}
+
+func (q Quantity) String() string {
+	n := strconv.FormatFloat(q.Value, 'g', -1, 64)
+	if q.Unit == "" {
+		return n
+	}
+	return n + " " + q.Unit
+}
+
+// Convert returns the quantity in another unit of the same dimension.
+func (q Quantity) Convert(to string) (Quantity, error) {
+	if q.Unit == to {
+		return q, nil
+	}
+
+	from, ok := units[q.Unit]
+	if !ok {
+		return Quantity{}, fmt.Errorf("unknown unit: %q", q.Unit)
+	}
+	target, ok := units[to]
+	if !ok {
+		return Quantity{}, fmt.Errorf("unknown unit: %q", to)
+	}
+	if from.dimension != target.dimension {
+		return Quantity{}, fmt.Errorf("can't convert %s to %s", q.Unit, to)
+	}
+
+	base := q.Value*from.scale + from.offset
+	return Quantity{Value: (base - target.offset) / target.scale, Unit: to}, nil
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	cases := map[string]Quantity{
+		"21.5 °C": {21.5, "°C"},
+		"21.5°C":  {21.5, "°C"},
+		"1200 W":  {1200, "W"},
+		"-3kWh":   {-3, "kWh"},
+		"42":      {42, ""},
+		"5eggs":   {5, "eggs"},
+		"5 eggs":  {5, "eggs"},
+		"1.5e3 W": {1500, "W"},
+		"2E-1kW":  {0.2, "kW"},
+	}
+	for in, want := range cases {
+		q, err := ParseQuantity(in)
+		if err != nil || q != want {
+			t.Errorf("%q: got %+v, %v", in, q, err)
+		}
+	}
+
+	for _, in := range []string{"°C", "1 2", "1 -W"} {
+		if _, err := ParseQuantity(in); err == nil {
+			t.Errorf("%q: expected an error", in)
+		}
+	}
+	if s := (Quantity{21.5, "°C"}).String(); s != "21.5 °C" {
+		t.Errorf("unexpected string: %q", s)
+	}
+}
+
+func TestConvertQuantity(t *testing.T) {
+	cases := []struct {
+		from Quantity
+		to   Quantity
+	}{
+		{Quantity{212, "°F"}, Quantity{100, "°C"}},
+		{Quantity{100, "°C"}, Quantity{212, "°F"}},
+		{Quantity{0, "°C"}, Quantity{273.15, "K"}},
+		{Quantity{1.2, "kW"}, Quantity{1200, "W"}},
+		{Quantity{1500, "Wh"}, Quantity{1.5, "kWh"}},
+		{Quantity{7, "lx"}, Quantity{7, "lx"}},
+	}
+	for _, c := range cases {
+		q, err := c.from.Convert(c.to.Unit)
+		if err != nil || q.Unit != c.to.Unit || math.Abs(q.Value-c.to.Value) > 1e-9 {
+			t.Errorf("%v to %s: got %v, %v", c.from, c.to.Unit, q, err)
+		}
+	}
+
+	for _, to := range []string{"W", "lx"} {
+		if _, err := (Quantity{20, "°C"}).Convert(to); err == nil {
+			t.Errorf("expected an error converting °C to %s", to)
+		}
+	}
+}
+
+func TestQuantityValue(t *testing.T) {
+	var v Value
+	v.FromRaw([]byte("70 °F"))
+	if v.Type != QuantityValue {
+		t.Fatalf("expected FromRaw to detect a quantity, got %s", v.Type)
+	}
+
+	var s Value
+	s.FromRaw([]byte("3 lights"))
+	if s.Type != StringValue {
+		t.Errorf("expected FromRaw to keep text with unknown units, got %s", s.Type)
+	}
+
+	c, err := v.InUnit("°C")
+	if q, _ := c.AsQuantity(); err != nil || q.Unit != "°C" || math.Abs(q.Value-21.1111) > 1e-3 {
+		t.Fatalf("unexpected conversion: %+v, %v", c, err)
+	}
+	if n, err := NewNumberValue(21).InUnit("°C"); err != nil || n.Type != NumberValue {
+		t.Fatal("expected numbers to be left alone")
+	}
+
+	data, err := json.Marshal(NewQuantityValue(Quantity{21.5, "°C"}))
+	if err != nil || string(data) != `{"type":"quantity","value":{"value":21.5,"unit":"°C"}}` {
+		t.Errorf("unexpected JSON: %s, %v", data, err)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/BurntSushi/toml"
@@ -20,21 +23,122 @@ const (
 	NumberValue
 	BoolValue
 	ColorValue
+	DateTimeValue
+	QuantityValue
+	EnumValue
+	PercentValue
+	PointValue
+	MapValue
 )
 
+func (t ValueType) String() string {
+	switch t {
+	case RawValue:
+		return "raw"
+	case StringValue:
+		return "string"
+	case NumberValue:
+		return "number"
+	case BoolValue:
+		return "bool"
+	case ColorValue:
+		return "color"
+	case DateTimeValue:
+		return "datetime"
+	case QuantityValue:
+		return "quantity"
+	case EnumValue:
+		return "enum"
+	case PercentValue:
+		return "percent"
+	case PointValue:
+		return "point"
+	case MapValue:
+		return "map"
+	}
+	return "???"
+}
+
+func ParseValueType(s string) (ValueType, error) {
+	switch strings.ToLower(s) {
+	case "raw":
+		return RawValue, nil
+	case "string":
+		return StringValue, nil
+	case "number":
+		return NumberValue, nil
+	case "bool":
+		return BoolValue, nil
+	case "color":
+		return ColorValue, nil
+	case "datetime":
+		return DateTimeValue, nil
+	case "quantity":
+		return QuantityValue, nil
+	case "enum":
+		return EnumValue, nil
+	case "percent":
+		return PercentValue, nil
+	case "point":
+		return PointValue, nil
+	case "map":
+		return MapValue, nil
+	}
+	return 0, fmt.Errorf("invalid value type: %s", s)
+}
+
 type Color struct {
 	H float64
 	S float64
 	V float64
 }
 
+// colorFields drops the methods of Color, so that it is encoded as a TOML
+// table rather than as text.
+type colorFields Color
+
+// ParseColor parses a color in its compact text form "H,S,V", or as the
+// TOML table AsString encodes colors as.
+func ParseColor(s string) (Color, error) {
+	if parts := strings.Split(s, ","); len(parts) == 3 {
+		var hsv [3]float64
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return Color{}, fmt.Errorf("invalid color: %q", s)
+			}
+			hsv[i] = f
+		}
+		return Color{H: hsv[0], S: hsv[1], V: hsv[2]}, nil
+	}
+
+	c, _, err := parseColorTable(s)
+	return c, err
+}
+
+// parseColorTable parses the TOML table form of a color, and reports
+// whether all of H, S and V were given.
+func parseColorTable(s string) (Color, bool, error) {
+	var c colorFields
+	md, err := toml.Decode(s, &c)
+	if err != nil {
+		return Color{}, false, err
+	}
+	return Color(c), md.IsDefined("H") && md.IsDefined("S") && md.IsDefined("V"), nil
+}
+
 type Value struct {
-	Type   ValueType
-	number float64
-	string string
-	bool   bool
-	color  Color
-	raw    []byte
+	Type ValueType
+	// number also holds percents, and string enum symbols.
+	number   float64
+	string   string
+	bool     bool
+	color    Color
+	time     time.Time
+	quantity Quantity
+	point    Point
+	fields   map[string]Value
+	raw      []byte
 }
 
 func NewStringValue(s string) Value {
@@ -69,6 +173,29 @@ func NewColorValue(c Color) Value {
 	}
 }
 
+// NewDateTimeValue returns a value holding a point in time.
+func NewDateTimeValue(t time.Time) Value {
+	return Value{
+		Type: DateTimeValue,
+		time: t.Round(0),
+	}
+}
+
+func NewQuantityValue(q Quantity) Value {
+	return Value{
+		Type:     QuantityValue,
+		quantity: q,
+	}
+}
+
+// NewPointValue returns a value holding a geographic location.
+func NewPointValue(p Point) Value {
+	return Value{
+		Type:  PointValue,
+		point: p,
+	}
+}
+
 func (v Value) AsString() (string, error) {
 	switch v.Type {
 	case RawValue:
@@ -85,8 +212,22 @@ func (v Value) AsString() (string, error) {
 	case ColorValue:
 		buf := bytes.Buffer{}
 		enc := toml.NewEncoder(&buf)
-		enc.Encode(v.color)
+		enc.Encode(colorFields(v.color))
 		return buf.String(), nil
+	case DateTimeValue:
+		return v.time.Format(time.RFC3339Nano), nil
+	case QuantityValue:
+		return v.quantity.String(), nil
+	case EnumValue:
+		return v.string, nil
+	case PercentValue:
+		return strconv.FormatFloat(v.number, 'g', -1, 64), nil
+	case PointValue:
+		return v.point.String(), nil
+	case MapValue:
+		// The JSON object of the fields, which AsMap reads back.
+		data, err := json.Marshal(v.fields)
+		return string(data), err
 	default:
 		return "", fmt.Errorf("invalid value type: %d", v.Type)
 	}
@@ -116,6 +257,11 @@ func (v Value) AsBool() (bool, error) {
 		return v.bool, nil
 	case ColorValue:
 		return int64(v.color.V) != 0, nil
+	case EnumValue:
+		p, err := v.AsPercent()
+		return p != 0, err
+	case PercentValue:
+		return v.number != 0, nil
 	default:
 		return false, fmt.Errorf("invalid value type: %d", v.Type)
 	}
@@ -143,6 +289,14 @@ func (v Value) AsNumber() (float64, error) {
 		return 0, nil
 	case ColorValue:
 		return float64(v.color.V), nil
+	case DateTimeValue:
+		return float64(v.time.UnixNano()) / 1e9, nil
+	case QuantityValue:
+		return v.quantity.Value, nil
+	case EnumValue:
+		return v.AsPercent()
+	case PercentValue:
+		return v.number, nil
 	default:
 		return 0, fmt.Errorf("invalid value type: %d", v.Type)
 	}
@@ -182,9 +336,7 @@ func (v Value) AsRawValue() (Value, error) {
 func (v Value) AsColor() (Color, error) {
 	switch v.Type {
 	case StringValue:
-		c := &Color{}
-		_, err := toml.Decode(v.string, c)
-		return *c, err
+		return ParseColor(v.string)
 	case ColorValue:
 		return v.color, nil
 	default:
@@ -198,6 +350,127 @@ func (v Value) AsColorValue() (Value, error) {
 	return NewColorValue(c), err
 }
 
+// dateTimeLayouts are the ISO 8601 forms accepted for date/time strings.
+// Times without a zone are in local time.
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// AsDateTime converts the value to a time. Numbers are seconds since the
+// Unix epoch.
+func (v Value) AsDateTime() (time.Time, error) {
+	switch v.Type {
+	case StringValue:
+		for _, layout := range dateTimeLayouts {
+			if t, err := time.ParseInLocation(layout, v.string, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date/time string: %s", v.string)
+	case NumberValue:
+		sec, frac := math.Modf(v.number)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case DateTimeValue:
+		return v.time, nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid value type: %d", v.Type)
+	}
+}
+
+func (v Value) AsDateTimeValue() (Value, error) {
+	t, err := v.AsDateTime()
+	return NewDateTimeValue(t), err
+}
+
+// AsQuantity converts the value to a quantity. Numbers have no unit.
+func (v Value) AsQuantity() (Quantity, error) {
+	switch v.Type {
+	case StringValue:
+		return ParseQuantity(v.string)
+	case NumberValue:
+		return Quantity{Value: v.number}, nil
+	case QuantityValue:
+		return v.quantity, nil
+	default:
+		return Quantity{}, fmt.Errorf("invalid value type: %d", v.Type)
+	}
+}
+
+func (v Value) AsQuantityValue() (Value, error) {
+	q, err := v.AsQuantity()
+	return NewQuantityValue(q), err
+}
+
+// InUnit converts a quantity value to the given unit. Values without a
+// unit, like plain numbers, are taken to be in that unit already and are
+// returned as is.
+func (v Value) InUnit(unit string) (Value, error) {
+	if v.Type != QuantityValue || v.quantity.Unit == "" {
+		return v, nil
+	}
+	q, err := v.quantity.Convert(unit)
+	if err != nil {
+		return v, err
+	}
+	return NewQuantityValue(q), nil
+}
+
+// AsPoint converts the value to a point. Strings are parsed by ParsePoint.
+func (v Value) AsPoint() (Point, error) {
+	switch v.Type {
+	case StringValue:
+		return ParsePoint(v.string)
+	case PointValue:
+		return v.point, nil
+	default:
+		return Point{}, fmt.Errorf("invalid value type: %d", v.Type)
+	}
+}
+
+func (v Value) AsPointValue() (Value, error) {
+	p, err := v.AsPoint()
+	return NewPointValue(p), err
+}
+
+// As converts the value to the given type.
+func (v Value) As(t ValueType) (Value, error) {
+	switch t {
+	case RawValue:
+		return v.AsRawValue()
+	case StringValue:
+		return v.AsStringValue()
+	case NumberValue:
+		return v.AsNumberValue()
+	case BoolValue:
+		return v.AsBoolValue()
+	case ColorValue:
+		return v.AsColorValue()
+	case DateTimeValue:
+		return v.AsDateTimeValue()
+	case QuantityValue:
+		return v.AsQuantityValue()
+	case EnumValue:
+		return v.AsEnumValue()
+	case PercentValue:
+		return v.AsPercentValue()
+	case PointValue:
+		return v.AsPointValue()
+	case MapValue:
+		return v.AsMapValue()
+	default:
+		return Value{}, fmt.Errorf("invalid value type: %d", t)
+	}
+}
+
+// ParseValue parses s as a value of a known type rather than guessing the
+// type like FromRaw does.
+func ParseValue(t ValueType, s string) (Value, error) {
+	return NewStringValue(s).As(t)
+}
+
 func (v *Value) FromRaw(bs []byte) {
 	if utf8.Valid(bs) {
 		*v = Value{
@@ -209,12 +482,40 @@ func (v *Value) FromRaw(bs []byte) {
 	v.unStringify()
 }
 
+// looksLikePoint tells points from numbers with thousands separators like
+// "1,000": the coordinates are separated by a comma and a space, or both
+// have decimals.
+func looksLikePoint(s string) bool {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 {
+		return false
+	}
+	if strings.HasPrefix(parts[1], " ") {
+		return true
+	}
+	return strings.Contains(parts[0], ".") && strings.Contains(parts[1], ".")
+}
+
 func (v *Value) unStringify() {
 	if v.Type != StringValue {
 		return
 	}
 
-	if newV, err := v.AsColorValue(); err == nil {
+	// Only the table form of colors is unambiguous. "H,S,V" looks like any
+	// other triple, and is only read as a color for items declaring it.
+	if c, complete, err := parseColorTable(v.string); err == nil && complete {
+		*v = NewColorValue(c)
+		return
+	}
+
+	if looksLikePoint(v.string) {
+		if newV, err := v.AsPointValue(); err == nil {
+			*v = newV
+			return
+		}
+	}
+
+	if newV, err := v.AsDateTimeValue(); err == nil {
 		*v = newV
 		return
 	}
@@ -228,4 +529,29 @@ func (v *Value) unStringify() {
 		*v = newV
 		return
 	}
+
+	if strings.HasSuffix(v.string, "%") {
+		if newV, err := v.AsPercentValue(); err == nil {
+			*v = newV
+			return
+		}
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(v.string), "{") {
+		if newV, err := v.AsMapValue(); err == nil {
+			*v = newV
+			return
+		}
+	}
+
+	if UpDownVocabulary.Contains(v.string) || IncreaseDecreaseVocabulary.Contains(v.string) {
+		*v = NewEnumValue(v.string)
+		return
+	}
+
+	// Only known units, so that text like "3 lights" stays a string.
+	if q, err := v.AsQuantity(); err == nil && KnownUnit(q.Unit) {
+		*v = NewQuantityValue(q)
+		return
+	}
 }
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDateTimeValue(t *testing.T) {
+	when := time.Date(2024, 5, 1, 6, 12, 0, 500000000, time.UTC)
+	v := NewDateTimeValue(when)
+
+	s, err := v.AsString()
+	if err != nil || s != "2024-05-01T06:12:00.5Z" {
+		t.Fatalf("unexpected string: %q, %v", s, err)
+	}
+	if n, err := v.AsNumber(); err != nil || n != 1714543920.5 {
+		t.Fatalf("unexpected number: %v, %v", n, err)
+	}
+
+	for _, in := range []Value{
+		NewStringValue(s),
+		NewNumberValue(1714543920.5),
+	} {
+		out, err := in.AsDateTime()
+		if err != nil || !out.Equal(when) {
+			t.Errorf("%+v: got %v, %v", in, out, err)
+		}
+	}
+
+	local, err := NewStringValue("2024-05-01").AsDateTime()
+	if err != nil || !local.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("unexpected date: %v, %v", local, err)
+	}
+
+	var raw Value
+	raw.FromRaw([]byte("2024-05-01T06:12:00+02:00"))
+	if raw.Type != DateTimeValue {
+		t.Errorf("expected FromRaw to detect a date/time, got %s", raw.Type)
+	}
+	raw.FromRaw([]byte("1714543920"))
+	if raw.Type != NumberValue {
+		t.Errorf("expected FromRaw to detect a number, got %s", raw.Type)
+	}
+
+	for _, in := range []string{
+		`{"type": "datetime", "value": "2024-05-01T06:12:00.5Z"}`,
+		`{"type": "datetime", "value": 1714543920.5}`,
+	} {
+		var out Value
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if got, _ := out.AsDateTime(); out.Type != DateTimeValue || !got.Equal(when) {
+			t.Errorf("%s: got %+v", in, out)
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil || string(data) != `{"type":"datetime","value":"2024-05-01T06:12:00.5Z"}` {
+		t.Errorf("unexpected JSON: %s, %v", data, err)
+	}
+}
@@ -0,0 +1,267 @@
+'use strict';
+
+// The dashboard keeps one element per item, grouped by the backend that
+// owns it, and updates them from the /events stream.
+
+const groups = document.getElementById('groups');
+const status = document.getElementById('status');
+const items = new Map();
+
+function backendOf(item) {
+  return (item.meta && item.meta.backend) || 'other';
+}
+
+// Values travel as {type, value}. Colors have h in degrees and s and v in
+// [0, 1].
+function typeOf(item) {
+  return item.value ? item.value.type : undefined;
+}
+
+// formatValue renders values without a dedicated control as text.
+function formatValue(v) {
+  if (!v) {
+    return '';
+  }
+  switch (v.type) {
+    case 'quantity':
+      return `${v.value.value} ${v.value.unit}`.trim();
+    case 'percent':
+      return `${v.value} %`;
+    case 'point':
+      return `${v.value.lat}, ${v.value.lon}`;
+    case 'map':
+      return Object.keys(v.value).sort()
+          .map((k) => `${k}: ${formatValue(v.value[k])}`).join(', ');
+    default:
+      return String(v.value);
+  }
+}
+
+function hsvToHex(c) {
+  const f = (n) => {
+    const k = (n + c.h / 60) % 6;
+    const v = c.v - c.v * c.s * Math.max(0, Math.min(k, 4 - k, 1));
+    return Math.round(v * 255).toString(16).padStart(2, '0');
+  };
+  return '#' + f(5) + f(3) + f(1);
+}
+
+function hexToHsv(hex) {
+  const r = parseInt(hex.slice(1, 3), 16) / 255;
+  const g = parseInt(hex.slice(3, 5), 16) / 255;
+  const b = parseInt(hex.slice(5, 7), 16) / 255;
+  const max = Math.max(r, g, b);
+  const d = max - Math.min(r, g, b);
+  let h = 0;
+  if (d !== 0) {
+    if (max === r) {
+      h = ((g - b) / d) % 6;
+    } else if (max === g) {
+      h = (b - r) / d + 2;
+    } else {
+      h = (r - g) / d + 4;
+    }
+  }
+  h *= 60;
+  if (h < 0) {
+    h += 360;
+  }
+  return {h: h, s: max === 0 ? 0 : d / max, v: max};
+}
+
+async function sendCommand(name, type, value) {
+  const resp = await fetch(`items/${encodeURIComponent(name)}/command`, {
+    method: 'POST',
+    headers: {'Content-Type': 'application/json'},
+    body: JSON.stringify({type: type, value: value}),
+  });
+  if (!resp.ok) {
+    const body = await resp.json().catch(() => ({}));
+    console.warn('command failed', name, body.error || resp.status);
+  }
+}
+
+function groupFor(backend) {
+  let section = groups.querySelector(`section[data-backend="${CSS.escape(backend)}"]`);
+  if (section) {
+    return section.querySelector('.items');
+  }
+
+  section = document.createElement('section');
+  section.dataset.backend = backend;
+  const title = document.createElement('h2');
+  title.textContent = backend;
+  const list = document.createElement('div');
+  list.className = 'items';
+  section.append(title, list);
+
+  const next = [...groups.children].find((s) => s.dataset.backend > backend);
+  groups.insertBefore(section, next || null);
+  return list;
+}
+
+function control(item) {
+  const name = item.name;
+  const meta = item.meta || {};
+  let input;
+
+  switch (typeOf(item)) {
+    case 'bool':
+      input = document.createElement('input');
+      input.type = 'checkbox';
+      input.addEventListener('change', () => {
+        sendCommand(name, 'bool', input.checked);
+      });
+      break;
+    case 'color':
+      input = document.createElement('input');
+      input.type = 'color';
+      input.addEventListener('change', () => {
+        sendCommand(name, 'color', hexToHsv(input.value));
+      });
+      break;
+    case 'number':
+      input = document.createElement('input');
+      input.type = 'range';
+      input.min = meta.min ?? 0;
+      input.max = meta.max ?? 100;
+      input.step = meta.step || 'any';
+      input.addEventListener('change', () => {
+        sendCommand(name, 'number', parseFloat(input.value));
+      });
+      break;
+    default:
+      input = document.createElement('span');
+      input.className = 'text';
+  }
+
+  if (meta.read_only) {
+    input.disabled = true;
+  }
+  return input;
+}
+
+function update(entry, item) {
+  const input = entry.input;
+  entry.error.textContent = item.error || '';
+
+  if (typeOf(item) !== entry.valueType) {
+    const replacement = control(item);
+    input.replaceWith(replacement);
+    entry.input = replacement;
+    entry.valueType = typeOf(item);
+    return update(entry, item);
+  }
+
+  // Don't fight the user while they're dragging a slider.
+  if (document.activeElement === input && input.type === 'range') {
+    return;
+  }
+
+  const value = item.value ? item.value.value : '';
+  switch (typeOf(item)) {
+    case 'bool':
+      input.checked = value;
+      break;
+    case 'color':
+      input.value = hsvToHex(value);
+      break;
+    case 'number': {
+      const n = value;
+      if (n > input.max) {
+        input.max = n;
+      }
+      if (n < input.min) {
+        input.min = n;
+      }
+      input.value = n;
+      input.title = value;
+      break;
+    }
+    default:
+      input.textContent = formatValue(item.value);
+  }
+}
+
+function upsert(item) {
+  let entry = items.get(item.name);
+  const backend = backendOf(item);
+
+  if (entry && entry.backend !== backend) {
+    remove(item.name);
+    entry = undefined;
+  }
+
+  if (!entry) {
+    const el = document.createElement('div');
+    el.className = 'item';
+    const label = document.createElement('div');
+    const name = document.createElement('div');
+    name.className = 'name';
+    name.textContent = (item.meta && item.meta.label) || item.name;
+    name.title = item.name;
+    const error = document.createElement('div');
+    error.className = 'error';
+    label.append(name, error);
+    const input = control(item);
+    el.append(label, input);
+
+    const list = groupFor(backend);
+    const next = [...list.children].find((e) => e.dataset.name > item.name);
+    el.dataset.name = item.name;
+    list.insertBefore(el, next || null);
+
+    entry = {el, input, error, backend, valueType: typeOf(item)};
+    items.set(item.name, entry);
+  }
+
+  update(entry, item);
+}
+
+function remove(name) {
+  const entry = items.get(name);
+  if (!entry) {
+    return;
+  }
+  items.delete(name);
+  const list = entry.el.parentElement;
+  entry.el.remove();
+  if (list && list.children.length === 0) {
+    list.parentElement.remove();
+  }
+}
+
+async function load() {
+  const resp = await fetch('items');
+  const list = await resp.json();
+  const names = new Set(list.map((item) => item.name));
+  for (const name of [...items.keys()]) {
+    if (!names.has(name)) {
+      remove(name);
+    }
+  }
+  list.forEach(upsert);
+}
+
+function connect() {
+  const events = new EventSource('events');
+
+  events.addEventListener('open', () => {
+    status.textContent = 'live';
+    status.classList.add('live');
+    // Catch up on anything missed while disconnected.
+    load().catch((err) => console.warn('loading items failed', err));
+  });
+
+  events.addEventListener('error', () => {
+    status.textContent = 'reconnecting';
+    status.classList.remove('live');
+  });
+
+  for (const type of ['added', 'changed']) {
+    events.addEventListener(type, (e) => upsert(JSON.parse(e.data)));
+  }
+  events.addEventListener('removed', (e) => remove(JSON.parse(e.data).name));
+}
+
+connect();
@@ -0,0 +1,22 @@
+// Package web holds the dashboard served by the bridge's HTTP API. It is a
+// static page that talks to the REST endpoints and follows /events for
+// live updates.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler serves the dashboard.
+func Handler() http.Handler {
+	root, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServer(http.FS(root))
+}
@@ -0,0 +1,205 @@
+package ws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/gorilla/websocket"
+)
+
+const reconnectInterval = 3 * time.Second
+
+// Client implements types.Bus by connecting to a Server. It reconnects
+// when the connection drops and restores its subscriptions afterwards.
+type Client struct {
+	cfg      Config
+	header   http.Header
+	received *delivery.Queue
+
+	mu     sync.Mutex
+	conn   *websocket.Conn
+	subs   subscriptions
+	closed bool
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if cfg.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(cfg.Username + ":" + cfg.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+
+	c := &Client{
+		cfg:      cfg,
+		header:   header,
+		received: delivery.NewQueue("websocket", cfg.Buffer, policy),
+		subs:     make(subscriptions),
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(cfg.Broker, header)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+
+	go c.run(conn)
+
+	return c, nil
+}
+
+func (c *Client) run(conn *websocket.Conn) {
+	for {
+		c.read(conn)
+
+		c.mu.Lock()
+		c.conn = nil
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			c.received.Close()
+			return
+		}
+
+		conn = c.reconnect()
+		if conn == nil {
+			c.received.Close()
+			return
+		}
+	}
+}
+
+func (c *Client) read(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			conn.Close()
+			return
+		}
+
+		var f frame
+		if err := json.Unmarshal(data, &f); err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("error decoding frame")
+			continue
+		}
+
+		switch f.Op {
+		case opMessage:
+			message, err := decodeMessage(f)
+			if err != nil {
+				log.WithFields(logrus.Fields{
+					"error": err,
+				}).Warn("error decoding message")
+				continue
+			}
+			c.received.Push(message)
+		case opError:
+			log.WithFields(logrus.Fields{
+				"error": f.Error,
+			}).Warn("websocket server reported an error")
+		}
+	}
+}
+
+func (c *Client) reconnect() *websocket.Conn {
+	for {
+		time.Sleep(reconnectInterval)
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil
+		}
+		c.mu.Unlock()
+
+		conn, _, err := websocket.DefaultDialer.Dial(c.cfg.Broker, c.header)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("error reconnecting to websocket server")
+			continue
+		}
+
+		c.mu.Lock()
+		c.conn = conn
+		for key := range c.subs {
+			sub, _ := subName(key.subType)
+			conn.WriteJSON(frame{Op: opSubscribe, Item: key.item, Sub: sub})
+		}
+		c.mu.Unlock()
+
+		return conn
+	}
+}
+
+func (c *Client) write(f frame) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return errors.New("not connected")
+	}
+	return c.conn.WriteJSON(f)
+}
+
+func (c *Client) Subscribe(itemName string, subType types.SubType) error {
+	sub, err := subName(subType)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.subs[subKey{itemName, subType}] = struct{}{}
+	c.mu.Unlock()
+
+	return c.write(frame{Op: opSubscribe, Item: itemName, Sub: sub})
+}
+
+func (c *Client) Unsubscribe(itemName string, subType types.SubType) error {
+	sub, err := subName(subType)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.subs, subKey{itemName, subType})
+	c.mu.Unlock()
+
+	return c.write(frame{Op: opUnsubscribe, Item: itemName, Sub: sub})
+}
+
+func (c *Client) Publish(message types.Message) error {
+	f, err := encodeMessage(opPublish, message)
+	if err != nil {
+		return err
+	}
+	return c.write(f)
+}
+
+func (c *Client) Messages() <-chan types.Message {
+	return c.received.Messages()
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+var _ types.Bus = &Client{}
@@ -0,0 +1,31 @@
+package ws
+
+import "github.com/catt-ha/catt-go/catt/delivery"
+
+// Config configures a Client.
+type Config struct {
+	// Broker is the websocket URL of a Server, e.g. ws://host:8080/bus.
+	Broker   string `toml:"broker"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	delivery.Config
+}
+
+// ServerConfig configures a Server.
+type ServerConfig struct {
+	// Listen is the address Listen serves the bus on. Defaults to
+	// 127.0.0.1:8080, so that the bus is only exposed when asked to.
+	Listen string `toml:"listen"`
+	// Path is the HTTP path of the websocket endpoint. Defaults to /bus.
+	Path string `toml:"path"`
+	// Username and Password, if set, are required from clients through
+	// basic auth.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// Origins lists the origins browsers may connect from. Same-origin
+	// requests are always allowed.
+	Origins []string `toml:"origins"`
+
+	delivery.Config
+}
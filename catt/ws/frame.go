@@ -0,0 +1,185 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+// Frames are the JSON messages exchanged over the websocket. Clients send
+// subscribe, unsubscribe and publish frames; the server sends message
+// frames for matching subscriptions and error frames for invalid requests.
+//
+//	{"op": "subscribe", "item": "Light_Switch", "sub": "state"}
+//	{"op": "publish", "item": "Light_Switch", "type": "command", "value": {"type": "bool", "value": true}}
+//	{"op": "message", "item": "Light_Switch", "type": "state", "value": {"type": "bool", "value": true}}
+//
+// The item "+" subscribes to every item. A value given as a plain string is
+// interpreted like a raw MQTT payload.
+type frame struct {
+	Op    string          `json:"op"`
+	Item  string          `json:"item,omitempty"`
+	Sub   string          `json:"sub,omitempty"`
+	Type  string          `json:"type,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Meta  *types.Meta     `json:"meta,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+const (
+	opSubscribe   = "subscribe"
+	opUnsubscribe = "unsubscribe"
+	opPublish     = "publish"
+	opMessage     = "message"
+	opError       = "error"
+)
+
+func subName(subType types.SubType) (string, error) {
+	switch subType {
+	case types.UpdateSub:
+		return "state", nil
+	case types.CommandSub:
+		return "command", nil
+	case types.MetaSub:
+		return "meta", nil
+	case types.AllSub:
+		return "all", nil
+	default:
+		return "", fmt.Errorf("invalid sub type: %d", subType)
+	}
+}
+
+func parseSub(s string) (types.SubType, error) {
+	switch s {
+	case "state":
+		return types.UpdateSub, nil
+	case "command":
+		return types.CommandSub, nil
+	case "meta":
+		return types.MetaSub, nil
+	case "all":
+		return types.AllSub, nil
+	default:
+		return 0, fmt.Errorf("invalid sub type: %s", s)
+	}
+}
+
+func typeName(msgType types.MessageType) (string, error) {
+	switch msgType {
+	case types.UpdateMessage:
+		return "state", nil
+	case types.CommandMessage:
+		return "command", nil
+	case types.MetaMessage:
+		return "meta", nil
+	case types.ErrorMessage:
+		return "error", nil
+	default:
+		return "", fmt.Errorf("invalid message type: %d", msgType)
+	}
+}
+
+func encodeMessage(op string, message types.Message) (frame, error) {
+	t, err := typeName(message.Type)
+	if err != nil {
+		return frame{}, err
+	}
+
+	f := frame{
+		Op:   op,
+		Item: message.ItemName,
+		Type: t,
+	}
+
+	switch message.Type {
+	case types.MetaMessage:
+		f.Meta = message.Meta
+		return f, nil
+	case types.ErrorMessage:
+		f.Error = message.Error
+		return f, nil
+	}
+
+	if message.Value != nil {
+		if f.Value, err = json.Marshal(message.Value); err != nil {
+			return frame{}, err
+		}
+	}
+	return f, nil
+}
+
+func decodeMessage(f frame) (types.Message, error) {
+	message := types.Message{
+		ItemName: f.Item,
+	}
+
+	if f.Item == "" || f.Item == "+" {
+		return message, fmt.Errorf("invalid item name: %q", f.Item)
+	}
+
+	switch f.Type {
+	case "state":
+		message.Type = types.UpdateMessage
+	case "command":
+		message.Type = types.CommandMessage
+	case "meta":
+		message.Type = types.MetaMessage
+		if f.Meta == nil {
+			return message, fmt.Errorf("missing meta")
+		}
+		message.Meta = f.Meta
+		return message, nil
+	case "error":
+		message.Type = types.ErrorMessage
+		message.Error = f.Error
+		return message, nil
+	default:
+		return message, fmt.Errorf("invalid message type: %s", f.Type)
+	}
+
+	if len(f.Value) == 0 {
+		return message, fmt.Errorf("missing value")
+	}
+	value, raw, err := types.UnmarshalValue(f.Value)
+	if err != nil {
+		return message, err
+	}
+	message.Value = &value
+	message.Raw = raw
+
+	return message, nil
+}
+
+type subKey struct {
+	item    string
+	subType types.SubType
+}
+
+// subscriptions is a set of item subscriptions. It is not safe for
+// concurrent use.
+type subscriptions map[subKey]struct{}
+
+func (s subscriptions) matches(message types.Message) bool {
+	var subType types.SubType
+	switch message.Type {
+	case types.UpdateMessage:
+		subType = types.UpdateSub
+	case types.CommandMessage:
+		subType = types.CommandSub
+	case types.MetaMessage:
+		subType = types.MetaSub
+	case types.ErrorMessage:
+		// Errors have no sub type of their own.
+		subType = types.AllSub
+	}
+
+	for _, item := range []string{message.ItemName, "+"} {
+		for _, st := range []types.SubType{subType, types.AllSub} {
+			if _, ok := s[subKey{item, st}]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
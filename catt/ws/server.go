@@ -0,0 +1,382 @@
+package ws
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/catt-ha/catt-go/catt/delivery"
+	"github.com/catt-ha/catt-go/catt/logging"
+	"github.com/catt-ha/catt-go/catt/types"
+	"github.com/gorilla/websocket"
+)
+
+var log = logging.Logger("websocket")
+
+const (
+	defaultListen = "127.0.0.1:8080"
+	defaultPath   = "/bus"
+
+	sendBuffer = 64
+)
+
+// Server is a message hub that websocket clients connect to. It routes
+// published messages to every matching subscriber and keeps the last state
+// and meta of each item for new subscribers, like a broker with retained
+// messages.
+//
+// The server is also a types.Bus itself, so a bridge can run directly on
+// it while UIs and remote bridges connect over HTTP(S).
+type Server struct {
+	cfg      ServerConfig
+	upgrader websocket.Upgrader
+	received *delivery.Queue
+
+	// http is the server started by Listen, if any.
+	http *http.Server
+
+	mu       sync.Mutex
+	conns    map[*serverConn]struct{}
+	local    subscriptions
+	retained map[subKey]types.Message
+	closed   bool
+}
+
+type serverConn struct {
+	ws   *websocket.Conn
+	send chan frame
+	subs subscriptions
+}
+
+func NewServer(cfg ServerConfig) (*Server, error) {
+	policy, err := delivery.ParsePolicy(cfg.Overflow)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		cfg:      cfg,
+		received: delivery.NewQueue("websocket-server", cfg.Buffer, policy),
+		conns:    make(map[*serverConn]struct{}),
+		local:    make(subscriptions),
+		retained: make(map[subKey]types.Message),
+	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+
+	return s, nil
+}
+
+// Listen creates a Server and serves it on its own HTTP listener.
+func Listen(cfg ServerConfig) (*Server, error) {
+	if cfg.Listen == "" {
+		cfg.Listen = defaultListen
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+
+	s, err := NewServer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, s)
+
+	s.http = &http.Server{Handler: mux}
+
+	go func() {
+		if err := s.http.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("websocket server stopped")
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range s.cfg.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.cfg.Username == "" {
+		return true
+	}
+
+	user, pass, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(s.cfg.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(s.cfg.Password)) == 1
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="catt"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &serverConn{
+		ws:   ws,
+		send: make(chan frame, sendBuffer),
+		subs: make(subscriptions),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ws.Close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+
+	go c.writer()
+	s.reader(c)
+}
+
+func (c *serverConn) writer() {
+	for f := range c.send {
+		if err := c.ws.WriteJSON(f); err != nil {
+			break
+		}
+	}
+	c.ws.Close()
+}
+
+// enqueue hands a frame to the connection's writer. Clients that can't keep
+// up are disconnected rather than stalling the whole hub. Must be called
+// with the server lock held.
+func (s *Server) enqueue(c *serverConn, f frame) {
+	select {
+	case c.send <- f:
+	default:
+		log.WithFields(logrus.Fields{
+			"remote": c.ws.RemoteAddr().String(),
+		}).Warn("websocket client too slow, disconnecting")
+		s.drop(c)
+	}
+}
+
+// drop removes a connection. Must be called with the server lock held.
+func (s *Server) drop(c *serverConn) {
+	if _, ok := s.conns[c]; !ok {
+		return
+	}
+	delete(s.conns, c)
+	close(c.send)
+}
+
+func (s *Server) reader(c *serverConn) {
+	defer func() {
+		s.mu.Lock()
+		s.drop(c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// A frame that doesn't decode, e.g. because of an invalid value,
+		// is answered like any other invalid request.
+		var f frame
+		if err = json.Unmarshal(data, &f); err == nil {
+			err = s.handle(c, f)
+		}
+		if err != nil {
+			s.mu.Lock()
+			if _, ok := s.conns[c]; ok {
+				s.enqueue(c, frame{Op: opError, Error: err.Error()})
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) handle(c *serverConn, f frame) error {
+	switch f.Op {
+	case opSubscribe, opUnsubscribe:
+		subType, err := parseSub(f.Sub)
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.conns[c]; !ok {
+			return nil
+		}
+
+		key := subKey{f.Item, subType}
+		if f.Op == opUnsubscribe {
+			delete(c.subs, key)
+			return nil
+		}
+
+		c.subs[key] = struct{}{}
+		for _, message := range s.retainedFor(key) {
+			if f, err := encodeMessage(opMessage, message); err == nil {
+				s.enqueue(c, f)
+			}
+		}
+		return nil
+	case opPublish:
+		message, err := decodeMessage(f)
+		if err != nil {
+			return err
+		}
+		return s.Publish(message)
+	default:
+		return fmt.Errorf("invalid op: %s", f.Op)
+	}
+}
+
+// retainedFor returns the retained messages matching a subscription. Must
+// be called with the server lock held.
+func (s *Server) retainedFor(key subKey) []types.Message {
+	subs := subscriptions{key: struct{}{}}
+	var messages []types.Message
+	for _, message := range s.retained {
+		if subs.matches(message) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+func (s *Server) Publish(message types.Message) error {
+	f, err := encodeMessage(opMessage, message)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+
+	switch message.Type {
+	case types.UpdateMessage:
+		s.retained[subKey{message.ItemName, types.UpdateSub}] = message
+	case types.MetaMessage:
+		s.retained[subKey{message.ItemName, types.MetaSub}] = message
+	}
+
+	for c := range s.conns {
+		if c.subs.matches(message) {
+			s.enqueue(c, f)
+		}
+	}
+
+	local := s.local.matches(message)
+	s.mu.Unlock()
+
+	// Pushing may block, so it must not happen under the lock.
+	if local {
+		s.received.Push(message)
+	}
+
+	return nil
+}
+
+func (s *Server) Subscribe(itemName string, subType types.SubType) error {
+	if _, err := subName(subType); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	key := subKey{itemName, subType}
+	s.local[key] = struct{}{}
+	retained := s.retainedFor(key)
+	s.mu.Unlock()
+
+	for _, message := range retained {
+		s.received.Push(message)
+	}
+
+	return nil
+}
+
+func (s *Server) Unsubscribe(itemName string, subType types.SubType) error {
+	if _, err := subName(subType); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.local, subKey{itemName, subType})
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Forget drops the retained state and meta of a removed item.
+func (s *Server) Forget(itemName string) error {
+	s.mu.Lock()
+	delete(s.retained, subKey{itemName, types.UpdateSub})
+	delete(s.retained, subKey{itemName, types.MetaSub})
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Server) Messages() <-chan types.Message {
+	return s.received.Messages()
+}
+
+// Close stops the HTTP server started by Listen, disconnects every client
+// and ends the Messages channel.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for c := range s.conns {
+		s.drop(c)
+	}
+	s.mu.Unlock()
+
+	var err error
+	if s.http != nil {
+		err = s.http.Close()
+	}
+	s.received.Close()
+	return err
+}
+
+var _ types.Bus = &Server{}
+var _ types.Forgetter = &Server{}
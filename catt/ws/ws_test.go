@@ -0,0 +1,177 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catt-ha/catt-go/catt/bus/bustest"
+	"github.com/catt-ha/catt-go/catt/types"
+)
+
+func TestWebsocket(t *testing.T) {
+	s, err := NewServer(ServerConfig{Username: "catt", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := httptest.NewServer(s)
+	defer hs.Close()
+	url := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	if _, err := NewClient(Config{Broker: url}); err == nil {
+		t.Fatal("expected connection without credentials to fail")
+	}
+
+	on := types.NewBoolValue(true)
+	if err := s.Publish(types.Message{
+		Type:     types.UpdateMessage,
+		ItemName: "Light_Switch",
+		Value:    &on,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Subscribe("Light_Switch", types.CommandSub); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(Config{Broker: url, Username: "catt", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Subscribe("+", types.UpdateSub); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bustest.Receive(t, c.Messages())
+	if msg.Type != types.UpdateMessage || msg.ItemName != "Light_Switch" || msg.Value.Type != types.BoolValue {
+		t.Fatalf("expected retained state, got %+v", msg)
+	}
+
+	off := types.NewBoolValue(false)
+	if err := c.Publish(types.Message{
+		Type:     types.CommandMessage,
+		ItemName: "Light_Switch",
+		Value:    &off,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = bustest.Receive(t, s.Messages())
+	if msg.Type != types.CommandMessage || msg.ItemName != "Light_Switch" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if b, err := msg.Value.AsBool(); err != nil || b {
+		t.Fatalf("unexpected value: %v", msg.Value)
+	}
+}
+
+func TestDecodeRawValue(t *testing.T) {
+	msg, err := decodeMessage(frame{
+		Op:    opPublish,
+		Item:  "Label",
+		Type:  "command",
+		Value: json.RawMessage(`"2024-05-01"`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Raw) != "2024-05-01" {
+		t.Fatalf("expected the raw string, got %+v", msg)
+	}
+
+	msg, err = decodeMessage(frame{
+		Op:    opPublish,
+		Item:  "Label",
+		Type:  "command",
+		Value: json.RawMessage(`{"type": "string", "value": "2024-05-01"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Value.Type != types.StringValue || msg.Raw != nil {
+		t.Fatalf("expected a typed string, got %+v", msg)
+	}
+
+	if _, err := decodeMessage(frame{Op: opPublish, Item: "Label", Type: "command", Value: json.RawMessage(`null`)}); err == nil {
+		t.Fatal("expected a null value to be rejected")
+	}
+}
+
+func TestForget(t *testing.T) {
+	s, err := NewServer(ServerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := httptest.NewServer(s)
+	defer hs.Close()
+
+	on := types.NewBoolValue(true)
+	for _, msg := range []types.Message{
+		{Type: types.UpdateMessage, ItemName: "Lamp", Value: &on},
+		{Type: types.MetaMessage, ItemName: "Lamp", Meta: &types.Meta{ValueType: "bool"}},
+		{Type: types.UpdateMessage, ItemName: "Fan", Value: &on},
+	} {
+		if err := s.Publish(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Forget("Lamp"); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClient(Config{Broker: "ws" + strings.TrimPrefix(hs.URL, "http")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Subscribe("+", types.AllSub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the item that is still around is replayed.
+	if msg := bustest.Receive(t, c.Messages()); msg.ItemName != "Fan" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	select {
+	case msg := <-c.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	addr := bustest.FreeAddress(t)
+	s, err := Listen(ServerConfig{Listen: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "ws://" + addr + defaultPath
+	c, err := NewClient(Config{Broker: url})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg, ok := <-s.Messages():
+		if ok {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	case <-time.After(bustest.Timeout):
+		t.Fatal("messages not closed")
+	}
+
+	if _, err := NewClient(Config{Broker: url}); err == nil {
+		t.Fatal("expected connection to a closed server to fail")
+	}
+}